@@ -0,0 +1,90 @@
+package branch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Baseline records the value of a metric for every function at some point,
+// so that functions already over their limit then do not fail later checks
+// unless they get worse. Functions are identified as in Diff, so moving a
+// function to another file of its package keeps its baseline.
+type Baseline struct {
+	// Metric is the metric that was recorded.
+	Metric Metric `json:"metric"`
+
+	// Policy is the counting policy the branch factors were computed with,
+	// with its weights as formatted by Policy.String, so that a custom
+	// policy reusing the name of another one is told apart.
+	Policy string `json:"policy"`
+
+	// Funcs maps the qualified identity of each function to its value.
+	Funcs map[string]uint `json:"functions"`
+}
+
+// NewBaseline returns the baseline of the metric for funcs, which were
+// analyzed with the counting policy (nil for DefaultPolicy). It fails if two
+// functions have the same identity, since only one of them could be recorded.
+func NewBaseline(funcs []*Func, metric Metric, policy *Policy) (*Baseline, error) {
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	keys, err := uniqueKeys(funcs)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{Metric: metric, Policy: policy.String(), Funcs: make(map[string]uint)}
+	for i, key := range keys {
+		b.Funcs[key] = metric.Value(funcs[i])
+	}
+	return b, nil
+}
+
+// ReadBaseline reads a baseline in the JSON form written by Write.
+func ReadBaseline(r io.Reader) (*Baseline, error) {
+	b := new(Baseline)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(b); err != nil {
+		return nil, fmt.Errorf("reading baseline: %v", err)
+	}
+	if b.Funcs == nil {
+		b.Funcs = make(map[string]uint)
+	}
+	return b, nil
+}
+
+// Write writes the baseline as a JSON object with the "metric", the "policy"
+// and a "functions" object from qualified identity to value, sorted by
+// identity so the file diffs well under version control.
+func (b *Baseline) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// Check is like l.Check, but leaves out the violations covered by the
+// baseline: those of functions whose value did not grow beyond their value in
+// the baseline. New functions and functions that got worse are still reported.
+// If l limits another metric than the baseline's, nothing is left out. Check
+// fails like NewBaseline if two functions have the same identity.
+func (b *Baseline) Check(l *Limits, funcs []*Func) ([]Violation, error) {
+	list, err := uniqueKeys(funcs)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[*Func]string)
+	for i, key := range list {
+		keys[funcs[i]] = key
+	}
+
+	var violations []Violation
+	for _, v := range l.Check(funcs) {
+		if old, ok := b.Funcs[keys[v.Func]]; ok && v.Metric == b.Metric && v.Value <= old {
+			continue
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
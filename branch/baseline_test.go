@@ -0,0 +1,81 @@
+package branch
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	init1 := &Func{ID: FuncID{Pkg: "m", Name: "init", Pos: token.Position{Filename: "/old/a.go", Line: 3}}, BranchFactor: 6}
+	funcs := []*Func{
+		{ID: FuncID{Pkg: "m", Name: "Small"}, BranchFactor: 2},
+		{ID: FuncID{Pkg: "m", Name: "Legacy"}, BranchFactor: 10},
+		{ID: FuncID{Pkg: "m", Recv: "*T", Name: "Legacy"}, BranchFactor: 8},
+		init1,
+	}
+	b, err := NewBaseline(funcs, MetricBranchFactor, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"metric": "branch"`, `"policy": "` + DefaultPolicy.String() + `"`, `"m.Legacy": 10`, `"m.(*T).Legacy": 8`, `"m.init@a.go#0": 6`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Write() = %s, want it to contain %s\n", &buf, want)
+		}
+	}
+	baseline, err := ReadBaseline(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// later: Legacy got better, (*T).Legacy got worse, init moved down and
+	// New is new
+	funcs = []*Func{
+		{ID: FuncID{Pkg: "m", Name: "Small"}, BranchFactor: 2},
+		{ID: FuncID{Pkg: "m", Name: "Legacy"}, BranchFactor: 9},
+		{ID: FuncID{Pkg: "m", Recv: "*T", Name: "Legacy"}, BranchFactor: 9},
+		{ID: FuncID{Pkg: "m", Name: "init", Pos: token.Position{Filename: "/new/a.go", Line: 20}}, BranchFactor: 6},
+		{ID: FuncID{Pkg: "m", Name: "New"}, BranchFactor: 7},
+	}
+	limits := &Limits{Metric: MetricBranchFactor, Max: 5}
+	violations, err := baseline.Check(limits, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Func.ID.String())
+	}
+	want := []string{"m.(*T).Legacy", "m.New"}
+	if !equalStrings(got, want) {
+		t.Errorf("Check() = %v, want %v\n", got, want)
+	}
+
+	// a baseline of another metric does not hide anything
+	limits.Metric = MetricCyclomatic
+	funcs[1].Cyclomatic = 6
+	if got, err := baseline.Check(limits, funcs); err != nil || len(got) != 1 {
+		t.Errorf("Check(cyclomatic) = %v, %v, want 1 violation\n", got, err)
+	}
+
+	// functions with the same identity cannot both be recorded
+	dup := []*Func{
+		{ID: FuncID{Pkg: "main", Name: "main"}, Pos: token.Position{Filename: "a/main.go", Line: 3}},
+		{ID: FuncID{Pkg: "main", Name: "main"}, Pos: token.Position{Filename: "b/main.go", Line: 3}},
+	}
+	if _, err := NewBaseline(dup, MetricBranchFactor, nil); err == nil {
+		t.Errorf("NewBaseline(duplicate functions) succeeded, want an error\n")
+	}
+	if _, err := baseline.Check(limits, dup); err == nil {
+		t.Errorf("Check(duplicate functions) succeeded, want an error\n")
+	}
+
+	if _, err := ReadBaseline(strings.NewReader(`{"metric": "lines"}`)); err == nil {
+		t.Errorf("ReadBaseline(unknown metric) succeeded, want an error\n")
+	}
+}
@@ -4,62 +4,191 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"math/big"
 )
 //func Inspect(node Node, f func(Node) bool)
 //Inspect traverses an AST in depth-first order: It starts by calling f(node); node must not be nil. 
 //If f returns true, 
 //Inspect invokes f recursively for each of the non-nil children of node, followed by a call of f(nil).
 //https://golang.org/pkg/go/ast/#Inspect
-func branchCount(fn *ast.FuncDecl) uint {
+func (w *walker) branchCount(fn ast.Node) uint {
 	// TODO: Write the branchCount function,
 	// count the number of branching statements in function fn
 	//https://play.golang.org/p/cq2OI6CA6v_n
 	var Count uint = 0
 	
-	ast.Inspect(fn, func (node ast.Node) bool{
+	w.inspect(fn, func (node ast.Node) bool{
 		//10-15lines
 		//detect if for switch range, type switch, goto, continue, break, fallthrough
+		//(and select, defer, else if), the policy decides how much each one counts
 		// If we return true, we keep recursing under this AST node.
-        // If we return false, we won't visit anything under this AST node.
-		switch node.(type) {
-		case *ast.IfStmt: //find if
-			Count++
-		case *ast.ForStmt: //find for
-			Count++
-		case *ast.SwitchStmt: //find switch
-			Count++
-		case *ast.RangeStmt: //find Range
-			Count++
-		case *ast.TypeSwitchStmt: //find type
-			Count++
-		case *ast.BranchStmt: //include goto, continue, break, fallthrough
-			Count++
-		// default:
-		// 	return false
-		}
-	
-	return true
-})
+		// If we return false, we won't visit anything under this AST node.
+		_, weight := w.count(node)
+		Count += weight
+		return true
+	})
 
 	return Count
 }
 
+// Func is the result of analyzing a single function declaration, or a single
+// function literal if Config.FuncLits is set.
+type Func struct {
+	// ID identifies the function, see FuncID.
+	ID FuncID
+
+	// Lit is set for a function literal reported on its own, see
+	// Config.FuncLits.
+	Lit bool
+
+	// Pos is the position of the func keyword of the declaration.
+	Pos token.Position
+
+	// BranchFactor is the number of branching statements, weighted by the
+	// counting policy, see branchCount and Policy.
+	BranchFactor uint
+
+	// Breakdown splits BranchFactor up by kind of branching statement.
+	Breakdown Breakdown
+
+	// Cyclomatic is the McCabe cyclomatic complexity, see cyclomatic.
+	Cyclomatic uint
+
+	// Cognitive is the cognitive complexity, which also penalizes nesting,
+	// see cognitive.
+	Cognitive uint
+
+	// Concurrency is the concurrency complexity, see concurrency.
+	Concurrency uint
+
+	// Nesting describes how deeply the statements are nested, see Nesting.
+	Nesting Nesting
+
+	// Unreachable holds the positions of statements that can never run, see
+	// Unreachable.
+	Unreachable []token.Position
+
+	// ConstConds holds the conditions that are compile-time constants and the
+	// switch cases that can never be chosen.
+	ConstConds []ConstCond
+
+	// Directives holds the //branch: comments in the doc comment of the
+	// function, see Directive.
+	Directives []Directive
+
+	// NPath is the number of acyclic execution paths, see npath.
+	NPath *big.Int
+
+	// Halstead holds the Halstead volume, difficulty and effort, see Halstead.
+	Halstead Halstead
+
+	// Lines counts the physical, logical and comment lines of the function.
+	Lines Lines
+
+	// Maintainability is the maintainability index of the function, see
+	// MIVariant.
+	Maintainability float64
+}
+
 // ComputeBranchFactors returns a map from the name of the function in the given
-// Go code to the number of branching statements it contains.
+// Go code to the number of branching statements it contains. Methods are keyed
+// by their receiver type too, e.g. "(*T).String", see FuncID.Local.
+// It panics if src is not a valid Go program, see BranchFactors for a variant
+// that returns the syntax errors instead.
 func ComputeBranchFactors(src string) map[string]uint {
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "src.go", src, 0)
+	m, err := BranchFactors("src.go", src)
 	if err != nil {
 		panic(err)
 	}
+	return m
+}
+
+// BranchFactors is like ComputeBranchFactors, but it does not panic on invalid
+// source. The source is read from src (string, []byte or io.Reader) or, if src
+// is nil, from the file filename.
+//
+// Every syntax error is reported in the returned error, which is then a
+// scanner.ErrorList whose entries carry the file, line and column of the error.
+// The map still holds the branch factor of every function the parser was able
+// to recover, so one bad function does not hide the rest of the file.
+func BranchFactors(filename string, src interface{}) (map[string]uint, error) {
+	funcs, err := AnalyzeSource(filename, src)
 
 	m := make(map[string]uint)
+	for _, fn := range funcs {
+		m[fn.ID.Local()] = fn.BranchFactor
+	}
+
+	return m, err
+}
+
+// AnalyzeSource parses a single Go file and returns the result for every
+// function declared in it, in source order, using the default Config.
+// Errors are reported as in BranchFactors.
+func AnalyzeSource(filename string, src interface{}) ([]*Func, error) {
+	return (*Config)(nil).AnalyzeSource(filename, src)
+}
+
+// AnalyzeSource is like the AnalyzeSource function, but uses the configuration
+// c.
+func (c *Config) AnalyzeSource(filename string, src interface{}) ([]*Func, error) {
+	c = c.config()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+	if f == nil {
+		// nothing could be parsed at all (e.g. the file could not be read)
+		return nil, err
+	}
+	info := c.typeCheck(fset, f.Name.Name, []*ast.File{f})
+	return c.analyzeFile(fset, f.Name.Name, f, info), err
+}
+
+// analyzeFile analyzes every function declared in f, which belongs to the
+// package pkg with the type information info. If c.FuncLits is set, each
+// function is followed by the function literals inside it.
+func (c *Config) analyzeFile(fset *token.FileSet, pkg string, f *ast.File, info *types.Info) []*Func {
+	w := c.walker(info)
+	w.comments = f.Comments
+
+	var funcs []*Func
 	for _, decl := range f.Decls {
 		switch fn := decl.(type) {
 		case *ast.FuncDecl:
-			m[fn.Name.Name] = branchCount(fn)
+			id := newFuncID(fset, pkg, fn)
+			result := w.analyze(fset, id, fn)
+			result.Directives = parseDirectives(fset, fn.Doc)
+			funcs = append(funcs, result)
+			if c.FuncLits && fn.Body != nil {
+				funcs = append(funcs, w.funcLits(fset, id, id.Name+".func", fn.Body)...)
+			}
 		}
 	}
+	return funcs
+}
 
-	return m
+// analyze computes the metrics of the function fn (a *ast.FuncDecl or a
+// *ast.FuncLit).
+func (w *walker) analyze(fset *token.FileSet, id FuncID, fn ast.Node) *Func {
+	var doc *ast.CommentGroup
+	if decl, ok := fn.(*ast.FuncDecl); ok {
+		doc = decl.Doc
+	}
+	result := &Func{
+		ID:           id,
+		Pos:          fset.Position(fn.Pos()),
+		BranchFactor: w.branchCount(fn),
+		Breakdown:    w.breakdown(fn),
+		Cyclomatic:   w.cyclomatic(fn),
+		Cognitive:    w.cognitive(fn),
+		Concurrency:  w.concurrency(fn),
+		Nesting:      w.nesting(fset, fn),
+		Unreachable:  Unreachable(fset, fn, w.constEval()),
+		ConstConds:   w.constConds(fset, fn),
+		NPath:        npath(fn),
+		Halstead:     w.halstead(fn),
+		Lines:        w.funcLines(fset, fn, doc),
+	}
+	result.Maintainability = w.miVariant.Index(result.Halstead.Volume, result.BranchFactor, result.Lines)
+	return result
 }
@@ -2,6 +2,7 @@ package branch
 
 import (
 	// "fmt"
+	"go/scanner"
 	"testing"
 )
 
@@ -270,3 +271,72 @@ func TestComputeBranchFactors_Fail(t *testing.T) {
 	}()
 
 }
+
+func TestBranchFactors_Errors(t *testing.T) {
+	var test_code = `package main
+
+func good_before() {
+	if true {
+	}
+}
+
+func good_after() {
+	for {
+		break
+	}
+}
+
+func broken() {
+	x := )
+	if x {
+	}
+}
+`
+	branch_factors, err := BranchFactors("bad.go", test_code)
+	if err == nil {
+		t.Fatalf("BranchFactors did not return an error, but should\n")
+	}
+
+	errs, ok := err.(scanner.ErrorList)
+	if !ok {
+		t.Fatalf("BranchFactors returned %T, want scanner.ErrorList\n", err)
+	}
+	if len(errs) < 2 {
+		t.Errorf("BranchFactors reported %d errors, want all of them (at least 2)\n", len(errs))
+	}
+	for _, e := range errs {
+		if e.Pos.Filename != "bad.go" || e.Pos.Line == 0 || e.Pos.Column == 0 {
+			t.Errorf("error %q has no source position\n", e)
+		}
+	}
+	if errs[0].Pos.Line != 15 || errs[0].Pos.Column != 7 {
+		t.Errorf("first error is at %d:%d, want 15:7\n", errs[0].Pos.Line, errs[0].Pos.Column)
+	}
+
+	tests := []struct {
+		name     string
+		branches uint
+	}{
+		{"good_before", 1},
+		{"good_after", 2},
+	}
+	for _, test := range tests {
+		if branch, ok := branch_factors[test.name]; !ok || branch != test.branches {
+			t.Errorf("BranchFactors(%v) = %d, want %d\n",
+				test.name, branch, test.branches)
+		}
+	}
+}
+
+// analyzeByName analyzes src and returns the results keyed by FuncID.Local.
+func analyzeByName(t *testing.T, src string) map[string]*Func {
+	funcs, err := AnalyzeSource("src.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeSource returned error %v\n", err)
+	}
+	results := make(map[string]*Func)
+	for _, fn := range funcs {
+		results[fn.ID.Local()] = fn
+	}
+	return results
+}
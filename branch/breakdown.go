@@ -0,0 +1,66 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// Breakdown splits the branch factor of a function up by kind of branching
+// statement.
+type Breakdown struct {
+	// Total is the branch factor.
+	Total uint
+
+	// Kinds maps each kind to what its statements add to the branch factor,
+	// which is their number times the weight of the kind in the policy.
+	// Kinds that do not occur or do not count are left out.
+	Kinds map[Kind]uint
+}
+
+// Loops returns the number of for and range statements.
+func (b Breakdown) Loops() uint {
+	return b.Kinds[KindFor] + b.Kinds[KindRange]
+}
+
+// Conditionals returns the number of if, else if, switch, type switch and
+// select statements and communication clauses.
+func (b Breakdown) Conditionals() uint {
+	return b.Kinds[KindIf] + b.Kinds[KindElseIf] + b.Kinds[KindSwitch] + b.Kinds[KindTypeSwitch] +
+		b.Kinds[KindSelect] + b.Kinds[KindCommClause]
+}
+
+// Jumps returns the number of goto, break, continue and fallthrough
+// statements.
+func (b Breakdown) Jumps() uint {
+	return b.Kinds[KindGoto] + b.Kinds[KindBreak] + b.Kinds[KindContinue] + b.Kinds[KindFallthrough]
+}
+
+// breakdown counts the branching statements of fn by kind.
+func (w *walker) breakdown(fn ast.Node) Breakdown {
+	b := Breakdown{Kinds: make(map[Kind]uint)}
+	w.inspect(fn, func(node ast.Node) bool {
+		if kind, weight := w.count(node); weight > 0 {
+			b.Kinds[kind] += weight
+			b.Total += weight
+		}
+		return true
+	})
+	return b
+}
+
+// MarshalText implements encoding.TextMarshaler, so kinds are written by name,
+// e.g. as keys of Breakdown.Kinds in JSON.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for i, name := range kindNames {
+		if name == string(text) {
+			*k = Kind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown branch kind %q", text)
+}
@@ -0,0 +1,93 @@
+package branch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBreakdown(t *testing.T) {
+	var test_code = `package main
+
+func loop_heavy(xs [][]int) {
+	for _, row := range xs {
+		for i := range row {
+			for j := 0; j < i; j++ {
+			}
+		}
+	}
+	if xs == nil {
+	}
+}
+
+func jump_heavy(x int) {
+	switch x {
+	case 0:
+		fallthrough
+	case 1:
+		goto end
+	}
+	for {
+		if x > 0 {
+			break
+		}
+		continue
+	}
+end:
+}
+`
+
+	tests := []struct {
+		name         string
+		kinds        map[Kind]uint
+		loops        uint
+		conditionals uint
+		jumps        uint
+	}{
+		{"loop_heavy", map[Kind]uint{KindRange: 2, KindFor: 1, KindIf: 1}, 3, 1, 0},
+		{"jump_heavy", map[Kind]uint{KindSwitch: 1, KindFallthrough: 1, KindGoto: 1,
+			KindFor: 1, KindIf: 1, KindBreak: 1, KindContinue: 1}, 1, 2, 4},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		b := results[test.name].Breakdown
+		if b.Total != results[test.name].BranchFactor {
+			t.Errorf("breakdown(%v).Total = %d, want the branch factor %d\n",
+				test.name, b.Total, results[test.name].BranchFactor)
+		}
+		if len(b.Kinds) != len(test.kinds) {
+			t.Errorf("breakdown(%v) = %v, want %v\n", test.name, b.Kinds, test.kinds)
+		}
+		for kind, n := range test.kinds {
+			if b.Kinds[kind] != n {
+				t.Errorf("breakdown(%v)[%v] = %d, want %d\n", test.name, kind, b.Kinds[kind], n)
+			}
+		}
+		if b.Loops() != test.loops || b.Conditionals() != test.conditionals || b.Jumps() != test.jumps {
+			t.Errorf("breakdown(%v) has %d loops, %d conditionals, %d jumps, want %d, %d, %d\n",
+				test.name, b.Loops(), b.Conditionals(), b.Jumps(), test.loops, test.conditionals, test.jumps)
+		}
+	}
+}
+
+func TestKindText(t *testing.T) {
+	in := map[Kind]uint{KindTypeSwitch: 2, KindFallthrough: 1}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"fallthrough":1,"typeswitch":2}` {
+		t.Errorf("json.Marshal(%v) = %s\n", in, data)
+	}
+
+	var out map[Kind]uint
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[KindTypeSwitch] != 2 || out[KindFallthrough] != 1 {
+		t.Errorf("json.Unmarshal(%s) = %v, want %v\n", data, out, in)
+	}
+	if err := json.Unmarshal([]byte(`{"loop":1}`), &out); err == nil {
+		t.Errorf("json.Unmarshal accepted an unknown kind\n")
+	}
+}
@@ -0,0 +1,193 @@
+package branch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Version identifies the counting rules of the analysis. It is part of every
+// cache key, so it must be changed whenever a change to the analysis changes
+// its results, which invalidates all cached results.
+const Version = "branch/34"
+
+// Cache stores the results of analyzed directories on disk, so analyzing an
+// unchanged tree again only costs reading and hashing its files. It is safe
+// for use by several goroutines, and by several processes sharing the
+// directory.
+//
+// Results are stored per directory (however its name is spelled), keyed by
+// the content hashes of its files together with Version, the counting policy
+// and the other settings of the Config that change results. A directory is
+// the unit, not a file, because the files of a package are type-checked
+// together: a constant changed in one file can change the results of another. Directories with syntax errors are not
+// cached, and nothing is cached if Config.Importer is set, since the results
+// then depend on code outside the directory.
+type Cache struct {
+	dir string
+
+	hits, misses int64
+}
+
+// CacheStats counts the files whose results were found in the cache (Hits)
+// and those that had to be analyzed (Misses).
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// String formats the statistics for humans and implements the Stringer
+// interface for CacheStats.
+func (s CacheStats) String() string {
+	return fmt.Sprintf("cache: %d hits, %d misses", s.Hits, s.Misses)
+}
+
+// OpenCache returns the cache in directory dir, which is created if it does
+// not exist.
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Stats returns the hits and misses since the cache was opened.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   int(atomic.LoadInt64(&c.hits)),
+		Misses: int(atomic.LoadInt64(&c.misses)),
+	}
+}
+
+// cacheEntry is the form in which results are stored.
+type cacheEntry struct {
+	Version  string
+	Packages []*Package
+}
+
+// get returns the packages in dir stored under key, and counts n files as
+// hits, or as misses if there are none.
+func (c *Cache) get(key, dir string, n int) ([]*Package, bool) {
+	data, err := ioutil.ReadFile(c.file(key))
+	var entry cacheEntry
+	if err != nil || json.Unmarshal(data, &entry) != nil || entry.Version != Version {
+		atomic.AddInt64(&c.misses, int64(n))
+		return nil, false
+	}
+	atomic.AddInt64(&c.hits, int64(n))
+	// the file names are stored relative to dir, see put
+	renameFiles(entry.Packages, func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	})
+	for _, pkg := range entry.Packages {
+		pkg.Dir = dir
+	}
+	return entry.Packages, true
+}
+
+// put stores pkgs, the packages in dir, under key. The file names are stored
+// relative to dir, so that the entry serves every spelling of dir. Errors are
+// ignored: a result that cannot be stored is computed again next time.
+func (c *Cache) put(key, dir string, pkgs []*Package) {
+	// work on a copy, pkgs is returned to the caller
+	data, err := json.Marshal(pkgs)
+	if err != nil {
+		return
+	}
+	var stored []*Package
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return
+	}
+	dir = filepath.Clean(dir)
+	renameFiles(stored, func(name string) string {
+		rel, err := filepath.Rel(dir, filepath.FromSlash(name))
+		if err != nil {
+			return name
+		}
+		return filepath.ToSlash(rel)
+	})
+	data, err = json.Marshal(cacheEntry{Version, stored})
+	if err != nil {
+		return
+	}
+	// write to a temporary file first, so readers never see half an entry
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.file(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// renameFiles replaces every file name in the results of pkgs by rename(name).
+func renameFiles(pkgs []*Package, rename func(name string) string) {
+	pos := func(p *token.Position) {
+		if p.Filename != "" {
+			p.Filename = rename(p.Filename)
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			file.Name = rename(file.Name)
+			for _, fn := range file.Funcs {
+				pos(&fn.Pos)
+				pos(&fn.Nesting.Deepest)
+				if fn.ID.Pos.Filename != "" {
+					// identities use slashes on every system
+					fn.ID.Pos.Filename = filepath.ToSlash(rename(fn.ID.Pos.Filename))
+				}
+				for i := range fn.Unreachable {
+					pos(&fn.Unreachable[i])
+				}
+				for i := range fn.ConstConds {
+					pos(&fn.ConstConds[i].Pos)
+				}
+				for i := range fn.Directives {
+					pos(&fn.Directives[i].Pos)
+				}
+			}
+		}
+	}
+}
+
+// file returns the name of the file holding the entry for key.
+func (c *Cache) file(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// cacheKey returns the key of the results of the named files with the
+// contents srcs in dir, whose import path is importPath. The key does not
+// depend on how dir is spelled.
+func (c *Config) cacheKey(dir, importPath string, names []string, srcs [][]byte) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	h := sha256.New()
+	settings := struct {
+		Version, Policy, Dir, ImportPath string
+		FuncLits, ExcludeFuncLits        bool
+		ExcludeConstant                  bool
+		Maintainability                  MIVariant
+	}{Version, c.policy().String(), dir, importPath, c.FuncLits, c.ExcludeFuncLits, c.ExcludeConstant, c.Maintainability}
+	json.NewEncoder(h).Encode(settings)
+	for i, name := range names {
+		sum := sha256.Sum256(srcs[i])
+		io.WriteString(h, name+"\x00"+hex.EncodeToString(sum[:])+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,182 @@
+package branch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": `package a
+
+const debug = false
+
+// A has a doc comment.
+func A(x int) int {
+	if debug {
+		println(x)
+	}
+	for i := 0; i < x; i++ {
+		if i > 2 {
+			return i
+		}
+	}
+	return 0
+}
+`,
+		"a/b.go": "package a\n\nfunc B() {\n\tselect {}\n}\n",
+		"b/b.go": "package b\n\nfunc (t *T) M() {}\n",
+	})
+	cache, err := OpenCache(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(cfg *Config) string {
+		cfg.Cache = cache
+		pkgs, err := cfg.AnalyzeTree(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		r := &Report{Funcs: Funcs(pkgs), Policy: cfg.Policy}
+		for _, pkg := range pkgs {
+			r.Files = append(r.Files, pkg.Files...)
+		}
+		if err := r.WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	stats := func(want CacheStats) {
+		t.Helper()
+		if got := cache.Stats(); got != want {
+			t.Errorf("Stats() = %v, want %v\n", got, want)
+		}
+	}
+
+	want := report(&Config{})
+	stats(CacheStats{Hits: 0, Misses: 3})
+	if got := report(&Config{}); got != want {
+		t.Errorf("cached results differ:\n%s\nwant\n%s", got, want)
+	}
+	stats(CacheStats{Hits: 3, Misses: 3})
+
+	// another policy does not use the results of the default policy
+	report(&Config{Policy: StrictPolicy})
+	stats(CacheStats{Hits: 3, Misses: 6})
+
+	// changing a file invalidates its directory only
+	err = ioutil.WriteFile(filepath.Join(root, "b", "b.go"), []byte("package b\n\nfunc (t *T) M() {\n\tgoto L\nL:\n}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := report(&Config{})
+	stats(CacheStats{Hits: 5, Misses: 7})
+	if !strings.Contains(got, `"goto": 1`) {
+		t.Errorf("results of the changed file were not recomputed:\n%s", got)
+	}
+
+	// entries of another analyzer version are not used
+	entries, _ := filepath.Glob(filepath.Join(cache.dir, "*.json"))
+	for _, name := range entries {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = bytes.Replace(data, []byte(`"Version":"`+Version+`"`), []byte(`"Version":"branch/0"`), 1)
+		if err := ioutil.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report(&Config{})
+	stats(CacheStats{Hits: 5, Misses: 10})
+}
+
+func TestCacheSpelling(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": `package a
+
+const debug = false
+
+func init() {
+	if debug {
+		return
+	}
+}
+
+//branch:ignore generated
+func A(x int) int {
+	for {
+		if x > 0 {
+			return x
+		}
+	}
+	return 0
+}
+`,
+	})
+	cache, err := OpenCache(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	report := func(cfg *Config, dir string) string {
+		pkgs, err := cfg.AnalyzeDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		r := &Report{Funcs: Funcs(pkgs)}
+		for _, pkg := range pkgs {
+			r.Files = append(r.Files, pkg.Files...)
+			buf.WriteString(pkg.Dir + "\n")
+		}
+		// every position, including those the report leaves out
+		renameFiles(pkgs, func(name string) string {
+			buf.WriteString(name + "\n")
+			return name
+		})
+		if err := r.WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	// every spelling of the directory shares one entry, and a hit has the
+	// file names of the spelling it was asked for
+	for i, dir := range []string{"a", "./a/", filepath.Join(root, "a")} {
+		want := report(&Config{}, dir)
+		if got := report(&Config{Cache: cache}, dir); got != want {
+			t.Errorf("cached results for %v differ:\n%s\nwant\n%s", dir, got, want)
+		}
+		if got, want := cache.Stats(), (CacheStats{Hits: i, Misses: 1}); got != want {
+			t.Errorf("Stats() after %v = %v, want %v\n", dir, got, want)
+		}
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(cache.dir, "*.json"))
+	for _, name := range entries {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(`"a/a.go"`)) || bytes.Contains(data, []byte(root)) {
+			t.Errorf("cache entry %s holds file names that are not relative to the directory:\n%s", name, data)
+		}
+	}
+}
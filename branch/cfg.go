@@ -0,0 +1,539 @@
+package branch
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Block is a basic block of a control-flow graph: a sequence of statements
+// (and conditions) that run one after the other, followed by a jump to one of
+// its successors.
+type Block struct {
+	// Index is the index of the block in CFG.Blocks.
+	Index int
+
+	// Kind says where the block comes from, e.g. "entry", "if.then" or
+	// "for.body". It is only used to label the block.
+	Kind string
+
+	// Nodes are the statements of the block, and the conditions, tags and
+	// range expressions evaluated at its end.
+	Nodes []ast.Node
+
+	// Succs are the blocks control may continue in after this one.
+	Succs []*Block
+}
+
+// CFG is the intra-procedural control-flow graph of a function. Calls are not
+// followed, and function literals are opaque expressions; && and || are not
+// split up into blocks of their own.
+type CFG struct {
+	// Blocks holds all blocks, Blocks[0] is the entry block and Blocks[1] the
+	// exit block reached by every return, by a call to panic and by the
+	// end of the function. Blocks that control never reaches (e.g. after a
+	// return) are kept, see Reachable.
+	Blocks []*Block
+
+	// stmts maps every statement of the function to the block it starts in.
+	stmts map[ast.Stmt]*Block
+}
+
+// Entry returns the block control starts in.
+func (g *CFG) Entry() *Block { return g.Blocks[0] }
+
+// Exit returns the block control leaves the function from.
+func (g *CFG) Exit() *Block { return g.Blocks[1] }
+
+// BuildCFG builds the control-flow graph of the function fn, a *ast.FuncDecl
+// or *ast.FuncLit. Every if, for, range, switch, type switch and select
+// statement, labeled break and continue, goto, fallthrough, return and call to
+// the builtin panic becomes an edge of the graph.
+//
+// Conditions that are the constants true or false (possibly combined with !,
+// && and ||) only get the edge that is taken, so e.g. the body of an if false
+// is unreachable. If eval is not nil, it is asked about every condition and
+// operand first, so that named constants such as a const debug = false count
+// too.
+func BuildCFG(fn ast.Node, eval ConstEval) *CFG {
+	var body *ast.BlockStmt
+	switch f := fn.(type) {
+	case *ast.FuncDecl:
+		body = f.Body
+	case *ast.FuncLit:
+		body = f.Body
+	}
+
+	b := &builder{g: &CFG{stmts: make(map[ast.Stmt]*Block)}, labels: make(map[string]*labelBlocks), eval: eval}
+	b.current = b.newBlock("entry")
+	exit := b.newBlock("exit")
+	if body != nil {
+		b.stmtList(body.List)
+	}
+	b.edge(exit)
+	return b.g
+}
+
+// ConstEval returns the value of the boolean expression x and whether it is a
+// constant. The walker of an analysis with type information uses the constant
+// values found by go/types.
+type ConstEval func(x ast.Expr) (value, ok bool)
+
+// targets are the blocks break, continue and fallthrough jump to in the
+// innermost statements around the current one.
+type targets struct {
+	outer         *targets
+	breakTo       *Block
+	continueTo    *Block // nil in switch and select
+	fallthroughTo *Block // only in switch case clauses
+}
+
+// labelBlocks are the blocks a label stands for.
+type labelBlocks struct {
+	gotoTo     *Block // the labeled statement itself
+	breakTo    *Block
+	continueTo *Block
+}
+
+type builder struct {
+	g       *CFG
+	current *Block
+	targets *targets
+	labels  map[string]*labelBlocks
+	eval    ConstEval
+}
+
+func (b *builder) newBlock(kind string) *Block {
+	block := &Block{Index: len(b.g.Blocks), Kind: kind}
+	b.g.Blocks = append(b.g.Blocks, block)
+	return block
+}
+
+func (b *builder) add(node ast.Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+}
+
+// edge adds an edge from the current block to to.
+func (b *builder) edge(to *Block) {
+	b.current.Succs = append(b.current.Succs, to)
+}
+
+// jump adds an edge from the current block to to and continues in a new
+// block that nothing jumps to, so the statements after the jump are
+// unreachable.
+func (b *builder) jump(to *Block) {
+	b.edge(to)
+	b.current = b.newBlock("unreachable")
+}
+
+// labeled returns the blocks of the label called name.
+func (b *builder) labeled(name string) *labelBlocks {
+	lb := b.labels[name]
+	if lb == nil {
+		lb = &labelBlocks{gotoTo: b.newBlock("label." + name)}
+		b.labels[name] = lb
+	}
+	return lb
+}
+
+func (b *builder) stmtList(list []ast.Stmt) {
+	for _, s := range list {
+		b.stmt(s, nil)
+	}
+}
+
+// stmt adds the statement s to the graph. If s is labeled, lb holds the blocks
+// of its label.
+func (b *builder) stmt(s ast.Stmt, lb *labelBlocks) {
+	b.g.stmts[s] = b.current
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		b.stmtList(s.List)
+
+	case *ast.LabeledStmt:
+		lb := b.labeled(s.Label.Name)
+		b.edge(lb.gotoTo)
+		b.current = lb.gotoTo
+		b.g.stmts[s] = b.current // goto may reach the label even if nothing else does
+		b.stmt(s.Stmt, lb)
+
+	case *ast.ExprStmt:
+		b.add(s)
+		if isPanic(s.X) {
+			b.jump(b.g.Exit())
+		}
+
+	case *ast.ReturnStmt:
+		b.add(s)
+		b.jump(b.g.Exit())
+
+	case *ast.BranchStmt:
+		b.branch(s)
+
+	case *ast.IfStmt:
+		b.ifStmt(s)
+
+	case *ast.ForStmt:
+		b.forStmt(s, lb)
+
+	case *ast.RangeStmt:
+		b.rangeStmt(s, lb)
+
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			b.stmt(s.Init, nil)
+		}
+		if s.Tag != nil {
+			b.add(s.Tag)
+		}
+		b.caseClauses(s.Body.List, lb, "switch")
+
+	case *ast.TypeSwitchStmt:
+		if s.Init != nil {
+			b.stmt(s.Init, nil)
+		}
+		b.add(s.Assign)
+		b.caseClauses(s.Body.List, lb, "typeswitch")
+
+	case *ast.SelectStmt:
+		b.selectStmt(s, lb)
+
+	default:
+		// assignments, declarations, go, defer, send, inc/dec, empty
+		b.add(s)
+	}
+}
+
+// boolConst returns the value of the condition x and whether it is constant:
+// true or false (not declared by the program) or a constant according to
+// b.eval, possibly in parentheses and combined with !, && and ||.
+func (b *builder) boolConst(x ast.Expr) (value, ok bool) {
+	if b.eval != nil {
+		if value, ok := b.eval(x); ok {
+			return value, true
+		}
+	}
+	switch e := x.(type) {
+	case *ast.Ident:
+		if e.Obj == nil && (e.Name == "true" || e.Name == "false") {
+			return e.Name == "true", true
+		}
+	case *ast.ParenExpr:
+		return b.boolConst(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			value, ok := b.boolConst(e.X)
+			return !value, ok
+		}
+	case *ast.BinaryExpr:
+		x, xok := b.boolConst(e.X)
+		y, yok := b.boolConst(e.Y)
+		switch e.Op {
+		case token.LAND:
+			if xok && !x || yok && !y {
+				return false, true
+			}
+			return true, xok && yok
+		case token.LOR:
+			if xok && x || yok && y {
+				return true, true
+			}
+			return false, xok && yok
+		}
+	}
+	return false, false
+}
+
+// isPanic reports whether x is a call to the builtin panic.
+func isPanic(x ast.Expr) bool {
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	id, ok := call.Fun.(*ast.Ident)
+	return ok && id.Name == "panic" && id.Obj == nil
+}
+
+func (b *builder) branch(s *ast.BranchStmt) {
+	var to *Block
+	switch s.Tok {
+	case token.BREAK:
+		if s.Label != nil {
+			to = b.labeled(s.Label.Name).breakTo
+		} else {
+			for t := b.targets; t != nil && to == nil; t = t.outer {
+				to = t.breakTo
+			}
+		}
+	case token.CONTINUE:
+		if s.Label != nil {
+			to = b.labeled(s.Label.Name).continueTo
+		} else {
+			for t := b.targets; t != nil && to == nil; t = t.outer {
+				to = t.continueTo
+			}
+		}
+	case token.FALLTHROUGH:
+		if b.targets != nil {
+			to = b.targets.fallthroughTo
+		}
+	case token.GOTO:
+		to = b.labeled(s.Label.Name).gotoTo
+	}
+
+	b.add(s)
+	if to == nil {
+		// invalid program, e.g. break outside of a loop; treat it like
+		// the end of the function
+		to = b.g.Exit()
+	}
+	b.jump(to)
+}
+
+func (b *builder) ifStmt(s *ast.IfStmt) {
+	if s.Init != nil {
+		b.stmt(s.Init, nil)
+	}
+	b.add(s.Cond)
+	cond := b.current
+	then := b.newBlock("if.then")
+	done := b.newBlock("if.done")
+	els := done
+	if s.Else != nil {
+		els = b.newBlock("if.else")
+	}
+	if value, ok := b.boolConst(s.Cond); !ok {
+		cond.Succs = append(cond.Succs, then, els)
+	} else if value {
+		cond.Succs = append(cond.Succs, then)
+	} else {
+		cond.Succs = append(cond.Succs, els)
+	}
+
+	b.current = then
+	b.stmtList(s.Body.List)
+	b.edge(done)
+
+	if s.Else != nil {
+		b.current = els
+		b.stmt(s.Else, nil)
+		b.edge(done)
+	}
+	b.current = done
+}
+
+func (b *builder) forStmt(s *ast.ForStmt, lb *labelBlocks) {
+	if s.Init != nil {
+		b.stmt(s.Init, nil)
+	}
+	loop := b.newBlock("for.loop")
+	body := b.newBlock("for.body")
+	done := b.newBlock("for.done")
+	cont := loop
+	if s.Post != nil {
+		cont = b.newBlock("for.post")
+	}
+
+	b.edge(loop)
+	b.current = loop
+	value, isConst := true, true // without a condition only break leaves the loop
+	if s.Cond != nil {
+		b.add(s.Cond)
+		value, isConst = b.boolConst(s.Cond)
+	}
+	if !isConst || !value {
+		b.edge(done)
+	}
+	if !isConst || value {
+		b.edge(body)
+	}
+
+	b.loopBody(s.Body, lb, done, cont, body)
+
+	if s.Post != nil {
+		b.current = cont
+		b.add(s.Post)
+		b.edge(loop)
+	}
+	b.current = done
+}
+
+func (b *builder) rangeStmt(s *ast.RangeStmt, lb *labelBlocks) {
+	b.add(s.X)
+	loop := b.newBlock("range.loop")
+	body := b.newBlock("range.body")
+	done := b.newBlock("range.done")
+
+	b.edge(loop)
+	b.current = loop
+	b.edge(body)
+	b.edge(done)
+
+	b.loopBody(s.Body, lb, done, loop, body)
+	b.current = done
+}
+
+// loopBody adds the body of a loop, starting in block start, where break
+// jumps to done and continue to cont, which the body also ends in.
+func (b *builder) loopBody(body *ast.BlockStmt, lb *labelBlocks, done, cont, start *Block) {
+	if lb != nil {
+		lb.breakTo, lb.continueTo = done, cont
+	}
+	b.targets = &targets{outer: b.targets, breakTo: done, continueTo: cont}
+	b.current = start
+	b.stmtList(body.List)
+	b.edge(cont)
+	b.targets = b.targets.outer
+}
+
+// caseClauses adds the clauses of a switch or type switch statement, which
+// starts at the end of the current block.
+func (b *builder) caseClauses(clauses []ast.Stmt, lb *labelBlocks, kind string) {
+	head := b.current
+	done := b.newBlock(kind + ".done")
+	if lb != nil {
+		lb.breakTo = done
+	}
+
+	bodies := make([]*Block, len(clauses))
+	hasDefault := false
+	for i, c := range clauses {
+		cc := c.(*ast.CaseClause)
+		if cc.List == nil {
+			bodies[i] = b.newBlock(kind + ".default")
+			hasDefault = true
+		} else {
+			bodies[i] = b.newBlock(kind + ".case")
+		}
+		head.Succs = append(head.Succs, bodies[i])
+	}
+	if !hasDefault {
+		head.Succs = append(head.Succs, done)
+	}
+
+	for i, c := range clauses {
+		cc := c.(*ast.CaseClause)
+		t := &targets{outer: b.targets, breakTo: done}
+		if i+1 < len(clauses) {
+			t.fallthroughTo = bodies[i+1]
+		}
+		b.targets = t
+		b.current = bodies[i]
+		for _, x := range cc.List {
+			b.add(x)
+		}
+		b.stmtList(cc.Body)
+		b.edge(done)
+		b.targets = t.outer
+	}
+	b.current = done
+}
+
+func (b *builder) selectStmt(s *ast.SelectStmt, lb *labelBlocks) {
+	head := b.current
+	done := b.newBlock("select.done")
+	if lb != nil {
+		lb.breakTo = done
+	}
+
+	for _, c := range s.Body.List {
+		cc := c.(*ast.CommClause)
+		kind := "select.case"
+		if cc.Comm == nil {
+			kind = "select.default"
+		}
+		b.current = b.newBlock(kind)
+		head.Succs = append(head.Succs, b.current)
+		b.targets = &targets{outer: b.targets, breakTo: done}
+		if cc.Comm != nil {
+			b.add(cc.Comm)
+		}
+		b.stmtList(cc.Body)
+		b.edge(done)
+		b.targets = b.targets.outer
+	}
+	// a select without clauses blocks forever, so done stays unreachable
+	b.current = done
+}
+
+// Reachable reports for every block (by index) whether control can reach it
+// from the entry block.
+func (g *CFG) Reachable() []bool {
+	seen := make([]bool, len(g.Blocks))
+	var visit func(*Block)
+	visit = func(block *Block) {
+		if seen[block.Index] {
+			return
+		}
+		seen[block.Index] = true
+		for _, succ := range block.Succs {
+			visit(succ)
+		}
+	}
+	visit(g.Entry())
+	return seen
+}
+
+// Complexity returns the cyclomatic complexity of the graph, E - N + 2P, where
+// E and N are the number of edges and blocks reachable from the entry and P,
+// the number of connected components, is 1.
+func (g *CFG) Complexity() int {
+	reachable := g.Reachable()
+	nodes, edges := 0, 0
+	for _, block := range g.Blocks {
+		if !reachable[block.Index] {
+			continue
+		}
+		nodes++
+		edges += len(block.Succs)
+	}
+	return edges - nodes + 2
+}
+
+// WriteDot writes the graph in the Graphviz DOT language, as a digraph called
+// name. Every block is labeled with its index, kind and the first line of
+// each of its nodes; unreachable blocks are drawn dashed.
+func (g *CFG) WriteDot(w io.Writer, fset *token.FileSet, name string) error {
+	reachable := g.Reachable()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %s {\n", strconv.Quote(name))
+	fmt.Fprintf(&buf, "\tnode [shape=box fontname=\"monospace\"];\n")
+	for _, block := range g.Blocks {
+		label := fmt.Sprintf("%d: %s", block.Index, block.Kind)
+		for _, node := range block.Nodes {
+			label += "\n" + nodeLine(fset, node)
+		}
+		style := ""
+		if !reachable[block.Index] {
+			style = " style=dashed"
+		}
+		fmt.Fprintf(&buf, "\tb%d [label=%s%s];\n", block.Index, strconv.Quote(label), style)
+	}
+	for _, block := range g.Blocks {
+		for _, succ := range block.Succs {
+			fmt.Fprintf(&buf, "\tb%d -> b%d;\n", block.Index, succ.Index)
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// nodeLine returns the first line of the source of node, with "..." if there
+// are more.
+func nodeLine(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return fmt.Sprintf("%T", node)
+	}
+	s := buf.String()
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i]) + " ..."
+	}
+	return s
+}
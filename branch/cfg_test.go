@@ -0,0 +1,203 @@
+package branch
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// parseFuncs parses src and returns its function declarations by name.
+func parseFuncs(t *testing.T, src string) (*token.FileSet, map[string]*ast.FuncDecl) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "src.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := make(map[string]*ast.FuncDecl)
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			funcs[fn.Name.Name] = fn
+		}
+	}
+	return fset, funcs
+}
+
+func TestCFGComplexity(t *testing.T) {
+	var test_code = `package main
+
+func straight(x int) int {
+	x++
+	return x
+}
+
+func if_else(x int) int {
+	if x > 0 {
+		return 1
+	} else if x < 0 {
+		return -1
+	}
+	return 0
+}
+
+func loops(xs []int) {
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			continue
+		}
+	}
+	for range xs {
+		break
+	}
+	for {
+		if len(xs) > 0 {
+			break
+		}
+	}
+}
+
+func switches(x interface{}, c chan int) {
+	switch x {
+	case 1:
+		fallthrough
+	case 2:
+	default:
+	}
+	switch x.(type) {
+	case int:
+	case string:
+	}
+	select {
+	case <-c:
+	default:
+	}
+}
+
+func labeled(grid [][]int) {
+outer:
+	for _, row := range grid {
+		for _, v := range row {
+			if v < 0 {
+				continue outer
+			}
+			if v == 0 {
+				break outer
+			}
+		}
+	}
+}
+
+func gotos(x int) {
+retry:
+	x--
+	if x > 0 {
+		goto retry
+	}
+	if x < -10 {
+		panic("too small")
+	}
+}
+
+func forever() {
+	for {
+	}
+}
+`
+
+	// sameAsMcCabe is set if the graph agrees with cyclomatic, which it does
+	// for functions without && and ||, except that a for loop without a
+	// condition is no decision in the graph
+	tests := []struct {
+		name         string
+		complexity   int
+		sameAsMcCabe bool
+	}{
+		{"straight", 1, true},
+		{"if_else", 3, true},
+		{"loops", 5, false},
+		{"switches", 6, true},
+		{"labeled", 5, true},
+		{"gotos", 3, true},
+		{"forever", 2, true},
+	}
+
+	_, funcs := parseFuncs(t, test_code)
+	w := &walker{}
+	for _, test := range tests {
+		fn := funcs[test.name]
+		g := BuildCFG(fn, nil)
+		if c := g.Complexity(); c != test.complexity {
+			t.Errorf("BuildCFG(%v).Complexity() = %d, want %d\n", test.name, c, test.complexity)
+		}
+		if test.sameAsMcCabe && uint(g.Complexity()) != w.cyclomatic(fn) {
+			t.Errorf("BuildCFG(%v).Complexity() = %d, but cyclomatic is %d\n", test.name, g.Complexity(), w.cyclomatic(fn))
+		}
+	}
+}
+
+func TestCFGEdges(t *testing.T) {
+	var test_code = `package main
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`
+	_, funcs := parseFuncs(t, test_code)
+	g := BuildCFG(funcs["f"], nil)
+
+	entry := g.Entry()
+	if len(entry.Succs) != 2 || entry.Succs[0].Kind != "if.then" || entry.Succs[1].Kind != "if.done" {
+		t.Fatalf("entry block has successors %v, want if.then and if.done\n", kinds(entry.Succs))
+	}
+	for _, block := range entry.Succs {
+		if len(block.Succs) != 1 || block.Succs[0] != g.Exit() {
+			t.Errorf("block %s has successors %v, want exit\n", block.Kind, kinds(block.Succs))
+		}
+	}
+}
+
+func kinds(blocks []*Block) []string {
+	var list []string
+	for _, block := range blocks {
+		list = append(list, block.Kind)
+	}
+	return list
+}
+
+func TestCFGWriteDot(t *testing.T) {
+	var test_code = `package main
+
+func f(x int) {
+	for x > 0 {
+		x--
+	}
+	return
+	println("never")
+}
+`
+	fset, funcs := parseFuncs(t, test_code)
+	var buf bytes.Buffer
+	if err := BuildCFG(funcs["f"], nil).WriteDot(&buf, fset, "f"); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+
+	for _, want := range []string{
+		`digraph "f" {`,
+		`b0 [label="0: entry"];`,
+		`[label="2: for.loop\nx > 0"];`,
+		`x--`,
+		`b0 -> b2;`,
+		`println(\"never\")"`,
+		`style=dashed`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("WriteDot wrote\n%s\nwant it to contain %s\n", dot, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// cognitive returns the cognitive complexity of fn, following the
+// SonarSource definition. It uses the same walk as branchCount,
+// but keeps a stack of the nodes it is in to know how deeply a statement is
+// nested:
+//
+//	if, switch, type switch, select, for and range add 1 plus the nesting level
+//	else if and else add 1, without a nesting penalty
+//	labeled break and continue, and goto, add 1 (they break the linear flow)
+//	every run of the same boolean operator adds 1, so a && b && c adds 1 but
+//	a && b || c adds 2
+//
+// The bodies of the structures above, and of function literals nested in fn,
+// are one level deeper than the structure itself.
+func (w *walker) cognitive(fn ast.Node) uint {
+	var Count uint = 0
+	var stack []ast.Node
+	nesting := uint(0)
+
+	w.inspect(fn, func(node ast.Node) bool {
+		if node == nil {
+			// leaving the node on top of the stack
+			if top := stack[len(stack)-1]; top != fn && w.nests(top) {
+				nesting--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		switch n := node.(type) {
+		case *ast.IfStmt:
+			if w.isElseIf(n) {
+				Count++
+			} else {
+				Count += 1 + nesting
+			}
+			if _, ok := n.Else.(*ast.BlockStmt); ok {
+				Count++
+			}
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			Count += 1 + nesting
+		case *ast.BranchStmt:
+			if n.Tok == token.GOTO || n.Label != nil {
+				Count++
+			}
+		case *ast.BinaryExpr:
+			if isLogical(n) && !isLogical(parentExpr(stack)) {
+				Count += booleanRuns(n)
+			}
+		}
+
+		if node != fn && w.nests(node) {
+			nesting++
+		}
+		stack = append(stack, node)
+		return true
+	})
+
+	return Count
+}
+
+// nests reports whether node adds a nesting level for the code inside it.
+func (w *walker) nests(node ast.Node) bool {
+	if _, ok := node.(*ast.FuncLit); ok {
+		return true
+	}
+	return w.nestsBranches(node)
+}
+
+// isLogical reports whether node is a && or || expression.
+func isLogical(node ast.Node) bool {
+	b, ok := node.(*ast.BinaryExpr)
+	return ok && (b.Op == token.LAND || b.Op == token.LOR)
+}
+
+// parentExpr returns the innermost node of the stack that is not a
+// parenthesized expression, or nil if there is none.
+func parentExpr(stack []ast.Node) ast.Node {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if _, ok := stack[i].(*ast.ParenExpr); !ok {
+			return stack[i]
+		}
+	}
+	return nil
+}
+
+// booleanRuns returns the number of runs of the same operator in the boolean
+// expression x, read from left to right.
+func booleanRuns(x ast.Expr) uint {
+	var ops []token.Token
+	var collect func(ast.Expr)
+	collect = func(x ast.Expr) {
+		switch e := x.(type) {
+		case *ast.ParenExpr:
+			collect(e.X)
+		case *ast.BinaryExpr:
+			if e.Op == token.LAND || e.Op == token.LOR {
+				collect(e.X)
+				ops = append(ops, e.Op)
+				collect(e.Y)
+			}
+		}
+	}
+	collect(x)
+
+	var runs uint = 0
+	for i, op := range ops {
+		if i == 0 || ops[i-1] != op {
+			runs++
+		}
+	}
+	return runs
+}
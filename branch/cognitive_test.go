@@ -0,0 +1,105 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestCognitive(t *testing.T) {
+	var test_code = `package main
+
+func sequential_if(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+}
+
+func nested_for(n int) {
+	for i := 0; i < n; i++ { // +1
+		for j := 0; j < n; j++ { // +2
+			for k := 0; k < n; k++ { // +3
+			}
+		}
+	}
+}
+
+func else_chain(x int) int {
+	if x < 0 { // +1
+		return -1
+	} else if x > 0 { // +1
+		if x > 10 { // +2
+			return 2
+		}
+		return 1
+	} else { // +1
+		return 0
+	}
+}
+
+func booleans(a, b, c, d bool) bool {
+	if a && b && c { // +1 +1
+		return true
+	}
+	return a && b || c && d // +3
+}
+
+func jumps(xs []int) {
+outer:
+	for _, x := range xs { // +1
+		switch { // +2
+		case x < 0:
+			continue outer // +1
+		case x == 0:
+			break // no label, +0
+		}
+		if x > 100 { // +2
+			goto done // +1
+		}
+	}
+done:
+}
+
+func closure() {
+	go func() {
+		if true { // +2, nested in the function literal
+		}
+	}()
+}
+
+func selects(a chan int) {
+	for { // +1
+		select { // +2
+		case <-a:
+			return
+		}
+	}
+}
+`
+
+	tests := []struct {
+		name      string
+		cognitive uint
+	}{
+		{"sequential_if", 3},
+		{"nested_for", 6},
+		{"else_chain", 5},
+		{"booleans", 5},
+		{"jumps", 7},
+		{"closure", 2},
+		{"selects", 3},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		fn := results[test.name]
+		if fn == nil {
+			t.Errorf("AnalyzeSource did not report %v\n", test.name)
+			continue
+		}
+		if fn.Cognitive != test.cognitive {
+			t.Errorf("cognitive(%v) = %d, want %d\n", test.name, fn.Cognitive, test.cognitive)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// concurrency returns the concurrency complexity of fn, a figure for how much
+// of it deals with goroutines and synchronization. It adds 1 for
+//
+//	every select statement and every communication clause (but not default)
+//	every go statement
+//	every channel receive or send in the init statement or condition of an
+//	if, for or switch statement
+//	every pair of Lock and Unlock (or RLock and RUnlock) calls on the same
+//	expression, e.g. mu.Lock() and a deferred mu.Unlock()
+//
+// With type information, only the methods of sync.Mutex, sync.RWMutex and
+// sync.Locker count as Lock calls. Without it, or if the method could not be
+// resolved (e.g. since imports are not followed), they are matched by name.
+func (w *walker) concurrency(fn ast.Node) uint {
+	var Count uint = 0
+	locks := make(map[string]int)   // receiver + "." + Lock or RLock -> number
+	unlocks := make(map[string]int) // receiver + "." + Lock or RLock -> number
+
+	w.inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.SelectStmt, *ast.GoStmt:
+			Count++
+		case *ast.CommClause:
+			if n.Comm != nil {
+				Count++
+			}
+		case *ast.IfStmt:
+			Count += channelOps(n.Init) + channelOps(n.Cond)
+		case *ast.ForStmt:
+			Count += channelOps(n.Init) + channelOps(n.Cond)
+		case *ast.SwitchStmt:
+			Count += channelOps(n.Init) + channelOps(n.Tag)
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || len(n.Args) != 0 || !w.maybeSyncLock(sel) {
+				break
+			}
+			recv := types.ExprString(sel.X)
+			switch sel.Sel.Name {
+			case "Lock", "RLock":
+				locks[recv+"."+sel.Sel.Name]++
+			case "Unlock":
+				unlocks[recv+".Lock"]++
+			case "RUnlock":
+				unlocks[recv+".RLock"]++
+			}
+		}
+		return true
+	})
+
+	for key, n := range locks {
+		if unlocks[key] < n {
+			n = unlocks[key]
+		}
+		Count += uint(n)
+	}
+	return Count
+}
+
+// maybeSyncLock reports whether sel may select a method of sync.Mutex,
+// sync.RWMutex or sync.Locker, which is the case if w has no type information
+// about it.
+func (w *walker) maybeSyncLock(sel *ast.SelectorExpr) bool {
+	if w.info == nil || w.info.Uses == nil {
+		return true
+	}
+	obj := w.info.Uses[sel.Sel]
+	if obj == nil {
+		return true
+	}
+	fn, ok := obj.(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "sync"
+}
+
+// channelOps returns the number of channel receives and sends in node, which
+// may be nil.
+func channelOps(node ast.Node) uint {
+	var Count uint = 0
+	if node == nil {
+		return Count
+	}
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				Count++
+			}
+		case *ast.SendStmt:
+			Count++
+		}
+		return true
+	})
+	return Count
+}
@@ -0,0 +1,179 @@
+package branch
+
+import (
+	"go/importer"
+	"go/token"
+	"testing"
+)
+
+func TestSelectBranches(t *testing.T) {
+	var test_code = `package main
+
+func worker(jobs, results chan int, quit chan bool) {
+	for {
+		select {
+		case j := <-jobs:
+			results <- j
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
+`
+
+	tests := []struct {
+		policy   *Policy
+		branches uint
+	}{
+		{DefaultPolicy, 1},    // for
+		{ConcurrentPolicy, 4}, // for, select, 2 clauses
+		{&Policy{Name: "no-select", Weights: map[Kind]uint{KindFor: 1}}, 1},
+		{&Policy{Name: "select-only", Weights: map[Kind]uint{KindFor: 1, KindSelect: 1}}, 2},
+	}
+	for _, test := range tests {
+		funcs, err := (&Config{Policy: test.policy}).AnalyzeSource("src.go", test_code)
+		if err != nil {
+			t.Fatalf("AnalyzeSource returned error %v\n", err)
+		}
+		if funcs[0].BranchFactor != test.branches {
+			t.Errorf("branchCount(worker) under %v = %d, want %d\n", test.policy.Name, funcs[0].BranchFactor, test.branches)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	var test_code = `package main
+
+func sequential(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func fan_out(jobs []int, done chan bool) {
+	for _, j := range jobs {
+		go process(j)
+	}
+	go func() {
+		done <- true
+	}()
+}
+
+func worker(jobs chan int, quit chan bool) {
+	for {
+		select {
+		case j := <-jobs:
+			process(j)
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
+
+func poll(ready chan bool, c chan int) {
+	if <-ready {
+	}
+	for v := <-c; v > 0; v = <-c {
+	}
+	switch <-c {
+	}
+}
+
+func (s *S) locked() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rw.RLock()
+	s.rw.RUnlock()
+	s.other.Lock()
+}
+
+type S struct{ mu, rw, other sync.Mutex }
+
+type fakeLock struct{}
+
+func (fakeLock) Lock()   {}
+func (fakeLock) Unlock() {}
+
+func fake(l fakeLock) {
+	l.Lock()
+	l.Unlock()
+}
+
+func process(int) {}
+`
+
+	tests := []struct {
+		name        string
+		concurrency uint
+	}{
+		{"sequential", 0},
+		{"fan_out", 2},
+		{"worker", 3},
+		{"poll", 3},
+		{"(*S).locked", 2},
+		{"fake", 0},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		fn := results[test.name]
+		if fn == nil {
+			t.Errorf("AnalyzeSource did not report %v\n", test.name)
+			continue
+		}
+		if fn.Concurrency != test.concurrency {
+			t.Errorf("concurrency(%v) = %d, want %d\n", test.name, fn.Concurrency, test.concurrency)
+		}
+	}
+}
+
+func TestConcurrencyTypes(t *testing.T) {
+	var test_code = `package main
+
+import "sync"
+
+type S struct {
+	sync.Mutex
+	rw sync.RWMutex
+	l  sync.Locker
+	f  fakeLock
+}
+
+type fakeLock struct{}
+
+func (fakeLock) Lock()   {}
+func (fakeLock) Unlock() {}
+
+func (s *S) locked() {
+	s.Lock()
+	defer s.Unlock()
+	s.rw.RLock()
+	s.rw.RUnlock()
+	s.l.Lock()
+	s.l.Unlock()
+	s.f.Lock()
+	s.f.Unlock()
+}
+`
+
+	cfg := &Config{Importer: importer.ForCompiler(token.NewFileSet(), "source", nil)}
+	funcs, err := cfg.AnalyzeSource("src.go", test_code)
+	if err != nil {
+		t.Fatalf("AnalyzeSource returned error %v\n", err)
+	}
+	var locked *Func
+	for _, fn := range funcs {
+		if fn.ID.Local() == "(*S).locked" {
+			locked = fn
+		}
+	}
+	if locked == nil {
+		t.Fatalf("AnalyzeSource did not report (*S).locked\n")
+	}
+	if locked.Concurrency != 3 {
+		t.Errorf("concurrency((*S).locked) = %d, want 3\n", locked.Concurrency)
+	}
+}
@@ -0,0 +1,193 @@
+package branch
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// ConstCond is a condition whose value is known at compile time, so one of the
+// branches it chooses between is dead.
+type ConstCond struct {
+	Pos token.Position
+
+	// Kind is KindIf, KindElseIf or KindFor for the condition of such a
+	// statement, and KindSwitch for a case clause of a switch statement.
+	Kind Kind
+
+	// Value is the value of the condition. For a case clause it is always
+	// false: the case can never be chosen.
+	Value bool
+}
+
+// String formats the condition for humans and implements the Stringer
+// interface for ConstCond.
+func (c ConstCond) String() string {
+	if c.Kind == KindSwitch {
+		return fmt.Sprintf("%s: case is never chosen", c.Pos)
+	}
+	return fmt.Sprintf("%s: %s condition is always %v", c.Pos, c.Kind, c.Value)
+}
+
+// noImporter is the types.Importer used if Config.Importer is nil. It does not
+// import anything, so only the constants of the package itself are known.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, errors.New("imports are not followed")
+}
+
+// typeCheck type-checks the files of the package path and returns what it
+// found out about their expressions. Type errors (e.g. from imports that are
+// not followed) are ignored; the information is then incomplete, but the
+// constants that could be evaluated are still there.
+func (c *Config) typeCheck(fset *token.FileSet, path string, files []*ast.File) *types.Info {
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer: c.Importer,
+		Error:    func(error) {},
+	}
+	if conf.Importer == nil {
+		conf.Importer = noImporter{}
+	}
+
+	defer func() {
+		// go/types may give up on badly broken syntax trees; keep what
+		// was found so far
+		recover()
+	}()
+	conf.Check(path, fset, files, info)
+	return info
+}
+
+// boolValue returns the value of the condition x if it is a boolean constant.
+func (w *walker) boolValue(x ast.Expr) (value, ok bool) {
+	if w.info == nil || x == nil {
+		return false, false
+	}
+	tv, found := w.info.Types[x]
+	if !found || tv.Value == nil || tv.Value.Kind() != constant.Bool {
+		return false, false
+	}
+	return constant.BoolVal(tv.Value), true
+}
+
+// constEval returns w.boolValue as the constant evaluator of BuildCFG, or nil
+// without type information.
+func (w *walker) constEval() ConstEval {
+	if w.info == nil {
+		return nil
+	}
+	return w.boolValue
+}
+
+// constValue returns the constant value of x, or nil if it is not constant.
+func (w *walker) constValue(x ast.Expr) constant.Value {
+	if w.info == nil || x == nil {
+		return nil
+	}
+	return w.info.Types[x].Value
+}
+
+// deadCase reports whether the case clause cc of a switch with tag (nil for
+// a switch without tag) can never be chosen, since the tag and all of its
+// expressions are constants that differ.
+func (w *walker) deadCase(tag ast.Expr, cc *ast.CaseClause) bool {
+	if cc.List == nil {
+		return false // default
+	}
+	tagValue := constant.MakeBool(true)
+	if tag != nil {
+		if tagValue = w.constValue(tag); tagValue == nil {
+			return false
+		}
+	}
+	for _, x := range cc.List {
+		v := w.constValue(x)
+		if v == nil || v.Kind() != tagValue.Kind() && !isNumeric(v, tagValue) {
+			return false
+		}
+		if constant.Compare(v, token.EQL, tagValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumeric reports whether both values are numbers, which compare across
+// kinds (e.g. an int with a float).
+func isNumeric(x, y constant.Value) bool {
+	numeric := func(v constant.Value) bool {
+		switch v.Kind() {
+		case constant.Int, constant.Float, constant.Complex:
+			return true
+		}
+		return false
+	}
+	return numeric(x) && numeric(y)
+}
+
+// constConds returns the constant conditions and dead cases in fn.
+func (w *walker) constConds(fset *token.FileSet, fn ast.Node) []ConstCond {
+	var list []ConstCond
+	w.inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.IfStmt:
+			if value, ok := w.boolValue(n.Cond); ok {
+				kind, _ := w.kind(n)
+				list = append(list, ConstCond{Pos: fset.Position(n.Cond.Pos()), Kind: kind, Value: value})
+			}
+		case *ast.ForStmt:
+			if value, ok := w.boolValue(n.Cond); ok {
+				list = append(list, ConstCond{Pos: fset.Position(n.Cond.Pos()), Kind: KindFor, Value: value})
+			}
+		case *ast.SwitchStmt:
+			for _, c := range n.Body.List {
+				if cc := c.(*ast.CaseClause); w.deadCase(n.Tag, cc) {
+					list = append(list, ConstCond{Pos: fset.Position(cc.Pos()), Kind: KindSwitch})
+				}
+			}
+		}
+		return true
+	})
+	return list
+}
+
+// isConstant reports whether node is an if or for statement that does not
+// really branch since its condition is constant (apart from a for loop whose
+// condition is always true, which is a loop like for {}), or a switch
+// statement whose tag and case expressions are all constants, so the clause
+// that runs is known. Such statements do not count towards the branch factor
+// if w.excludeConst is set.
+func (w *walker) isConstant(node ast.Node) bool {
+	if !w.excludeConst {
+		return false
+	}
+	switch n := node.(type) {
+	case *ast.IfStmt:
+		_, ok := w.boolValue(n.Cond)
+		return ok
+	case *ast.ForStmt:
+		value, ok := w.boolValue(n.Cond)
+		return ok && !value
+	case *ast.SwitchStmt:
+		if n.Tag != nil && w.constValue(n.Tag) == nil {
+			return false
+		}
+		for _, c := range n.Body.List {
+			for _, x := range c.(*ast.CaseClause).List {
+				if w.constValue(x) == nil {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
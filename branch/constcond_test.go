@@ -0,0 +1,126 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestConstConds(t *testing.T) {
+	var test_code = `package main
+
+const debug = false
+const mode = "fast"
+
+func conditions(x int) {
+	if true {
+		x++
+	} else if debug {
+		x--
+	} else if x > 0 {
+		x = 0
+	}
+	for false {
+	}
+	for x < 10 {
+		x++
+	}
+	if len(mode) == 4 && !debug {
+		x = 4
+	}
+}
+
+func switches(x int) {
+	switch mode {
+	case "slow":
+	case "fast":
+	case "medium", "other":
+	}
+	switch {
+	case debug:
+	case x > 0:
+	}
+	switch x {
+	case 1:
+	}
+}
+
+func loop() {
+	for true {
+		break
+	}
+}
+`
+
+	tests := []struct {
+		name  string
+		conds []ConstCond
+		// branch factor without and with ExcludeConstant
+		branches, excluded uint
+	}{
+		{"conditions",
+			[]ConstCond{{Kind: KindIf, Value: true}, {Kind: KindElseIf, Value: false},
+				{Kind: KindFor, Value: false}, {Kind: KindIf, Value: true}},
+			6, 2},
+		{"switches",
+			[]ConstCond{{Kind: KindSwitch}, {Kind: KindSwitch}, {Kind: KindSwitch}},
+			3, 2},
+		{"loop",
+			[]ConstCond{{Kind: KindFor, Value: true}},
+			2, 2},
+	}
+	lines := map[string][]int{
+		"conditions": {7, 9, 14, 19},
+		"switches":   {26, 28, 31},
+		"loop":       {40},
+	}
+
+	results := analyzeByName(t, test_code)
+	funcs, err := (&Config{ExcludeConstant: true}).AnalyzeSource("src.go", test_code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := make(map[string]*Func)
+	for _, fn := range funcs {
+		excluded[fn.ID.Local()] = fn
+	}
+
+	for _, test := range tests {
+		conds := results[test.name].ConstConds
+		if len(conds) != len(test.conds) {
+			t.Errorf("constConds(%v) = %v, want %d conditions\n", test.name, conds, len(test.conds))
+			continue
+		}
+		for i, c := range conds {
+			want := test.conds[i]
+			if c.Kind != want.Kind || c.Value != want.Value || c.Pos.Line != lines[test.name][i] {
+				t.Errorf("constConds(%v)[%d] = %v (%v), want %v %v on line %d\n",
+					test.name, i, c, c.Kind, want.Kind, want.Value, lines[test.name][i])
+			}
+		}
+
+		if b := results[test.name].BranchFactor; b != test.branches {
+			t.Errorf("branchCount(%v) = %d, want %d\n", test.name, b, test.branches)
+		}
+		fn := excluded[test.name]
+		if fn.BranchFactor != test.excluded || fn.Breakdown.Total != test.excluded {
+			t.Errorf("branchCount(%v) without constant conditions = %d (breakdown %d), want %d\n",
+				test.name, fn.BranchFactor, fn.Breakdown.Total, test.excluded)
+		}
+	}
+}
+
+func TestConstCondsAcrossFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"consts.go": "package p\n\nconst enabled = false\n",
+		"p.go":      "package p\n\nfunc F(x int) {\n\tif enabled {\n\t\tx++\n\t}\n}\n",
+	})
+
+	pkgs, err := (&Config{ExcludeConstant: true}).AnalyzeDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := Funcs(pkgs)[0]
+	if len(fn.ConstConds) != 1 || fn.BranchFactor != 0 {
+		t.Errorf("F has constant conditions %v and branch factor %d, want 1 condition and 0\n",
+			fn.ConstConds, fn.BranchFactor)
+	}
+}
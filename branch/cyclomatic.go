@@ -0,0 +1,42 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// cyclomatic returns the McCabe cyclomatic complexity of fn, that is one plus
+// the number of decision points in it. Unlike branchCount, which counts a
+// switch once, every decision counts here:
+//
+//	if, for and range statements
+//	every case clause of a switch or type switch (but not default)
+//	every communication clause of a select (but not default)
+//	every && and ||, since both short-circuit
+//
+// Jump statements (goto, break, ...) are not decisions and do not count.
+func (w *walker) cyclomatic(fn ast.Node) uint {
+	var Count uint = 1
+
+	w.inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			Count++
+		case *ast.CaseClause:
+			if n.List != nil { // default has no expressions
+				Count++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil { // default has no communication
+				Count++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				Count++
+			}
+		}
+		return true
+	})
+
+	return Count
+}
@@ -0,0 +1,94 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestCyclomatic(t *testing.T) {
+	var test_code = `package main
+
+func straight() int {
+	return 42
+}
+
+func single_if(x int) {
+	if x > 0 {
+		x--
+	} else {
+		x++
+	}
+}
+
+func bool_ops(a, b, c bool) bool {
+	if a && b || c {
+		return true
+	}
+	return a && c
+}
+
+func switch_cases(x int) {
+	switch x {
+	case 0, 1:
+	case 2:
+	case 3:
+	default:
+	}
+}
+
+func type_switch(x interface{}) {
+	switch x.(type) {
+	case int:
+	case string:
+	}
+}
+
+func loops(xs []int) {
+	for i := 0; i < 10; i++ {
+		if i > 5 {
+			break
+		}
+	}
+	for range xs {
+		continue
+	}
+}
+
+func selects(a, b chan int) {
+	select {
+	case <-a:
+	case v := <-b:
+		_ = v
+	default:
+	}
+}
+`
+
+	tests := []struct {
+		name       string
+		cyclomatic uint
+		branches   uint
+	}{
+		{"straight", 1, 0},
+		{"single_if", 2, 1},
+		{"bool_ops", 5, 1},
+		{"switch_cases", 4, 1},
+		{"type_switch", 3, 1},
+		{"loops", 4, 5},
+		{"selects", 3, 0},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		fn := results[test.name]
+		if fn == nil {
+			t.Errorf("AnalyzeSource did not report %v\n", test.name)
+			continue
+		}
+		if fn.Cyclomatic != test.cyclomatic {
+			t.Errorf("cyclomatic(%v) = %d, want %d\n", test.name, fn.Cyclomatic, test.cyclomatic)
+		}
+		if fn.BranchFactor != test.branches {
+			t.Errorf("branchCount(%v) = %d, want %d\n", test.name, fn.BranchFactor, test.branches)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// Kind enumerates the kinds of statements branchCount can count. Which of
+// them count, and how much, is decided by the Policy.
+type Kind int
+
+// Enumerates all kinds of branching statements.
+const (
+	KindIf Kind = iota
+	KindFor
+	KindRange
+	KindSwitch
+	KindTypeSwitch
+	KindGoto
+	KindBreak
+	KindContinue
+	KindFallthrough
+	KindElseIf
+	KindSelect
+	KindDefer
+	KindCommClause
+)
+
+var kindNames = []string{
+	KindIf:          "if",
+	KindFor:         "for",
+	KindRange:       "range",
+	KindSwitch:      "switch",
+	KindTypeSwitch:  "typeswitch",
+	KindGoto:        "goto",
+	KindBreak:       "break",
+	KindContinue:    "continue",
+	KindFallthrough: "fallthrough",
+	KindElseIf:      "elseif",
+	KindSelect:      "select",
+	KindDefer:       "defer",
+	KindCommClause:  "commclause",
+}
+
+// String returns the name of the kind, e.g. "typeswitch", and implements the
+// Stringer interface for Kind.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Branch is a single branching statement of a function.
+type Branch struct {
+	Kind Kind
+	Pos  token.Position
+
+	// Depth is the number of if, for, range, switch, type switch and select
+	// statements the branch is nested in; the statements directly in the
+	// function body have depth 0. An else if has the depth of the if it
+	// belongs to.
+	Depth int
+}
+
+// String formats the branch for humans and implements the Stringer interface
+// for Branch.
+func (b Branch) String() string {
+	return fmt.Sprintf("%s: %s (depth %d)", b.Pos, b.Kind, b.Depth)
+}
+
+// kind returns the kind of node and whether it is a statement that may count
+// as a branch. The if statement of an else if is reported as KindElseIf.
+func (w *walker) kind(node ast.Node) (Kind, bool) {
+	switch n := node.(type) {
+	case *ast.IfStmt:
+		if w.isElseIf(n) {
+			return KindElseIf, true
+		}
+		return KindIf, true
+	case *ast.ForStmt:
+		return KindFor, true
+	case *ast.RangeStmt:
+		return KindRange, true
+	case *ast.SwitchStmt:
+		return KindSwitch, true
+	case *ast.TypeSwitchStmt:
+		return KindTypeSwitch, true
+	case *ast.SelectStmt:
+		return KindSelect, true
+	case *ast.DeferStmt:
+		return KindDefer, true
+	case *ast.CommClause:
+		if n.Comm != nil { // the default clause is not a communication
+			return KindCommClause, true
+		}
+	case *ast.BranchStmt:
+		switch n.Tok {
+		case token.GOTO:
+			return KindGoto, true
+		case token.BREAK:
+			return KindBreak, true
+		case token.CONTINUE:
+			return KindContinue, true
+		case token.FALLTHROUGH:
+			return KindFallthrough, true
+		}
+	}
+	return 0, false
+}
+
+// nestsBranches reports whether the statements inside node are one level
+// deeper than node itself. That is the case for the control structures, even
+// if the policy does not count them.
+func (w *walker) nestsBranches(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.IfStmt:
+		return !w.isElseIf(node)
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		return true
+	}
+	return false
+}
+
+// Branches returns every statement counted by branchCount in the function fn
+// (a *ast.FuncDecl or *ast.FuncLit) under the default policy, in source order,
+// with its kind, position and nesting depth.
+func Branches(fset *token.FileSet, fn ast.Node) []Branch {
+	return (&walker{}).branches(fset, fn)
+}
+
+func (w *walker) branches(fset *token.FileSet, fn ast.Node) []Branch {
+	var list []Branch
+	var stack []ast.Node
+	depth := 0
+
+	w.inspect(fn, func(node ast.Node) bool {
+		if node == nil {
+			if w.nestsBranches(stack[len(stack)-1]) {
+				depth--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		if kind, weight := w.count(node); weight > 0 {
+			d := depth
+			if kind == KindElseIf {
+				d-- // the else if is inside the if it belongs to
+			}
+			list = append(list, Branch{Kind: kind, Pos: fset.Position(node.Pos()), Depth: d})
+		}
+		if w.nestsBranches(node) {
+			depth++
+		}
+		stack = append(stack, node)
+		return true
+	})
+
+	return list
+}
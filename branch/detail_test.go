@@ -0,0 +1,105 @@
+package branch
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestBranches(t *testing.T) {
+	var test_code = `package main
+
+func f(xs []int, x interface{}) {
+	for i := 0; i < 10; i++ {
+		if i > 5 {
+			break
+		} else if i > 3 {
+			continue
+		}
+	}
+	for range xs {
+		switch x.(type) {
+		case int:
+			goto end
+		}
+	}
+	switch {
+	case true:
+		fallthrough
+	default:
+	}
+end:
+}
+`
+
+	tests := []struct {
+		kind  Kind
+		line  int
+		depth int
+	}{
+		{KindFor, 4, 0},
+		{KindIf, 5, 1},
+		{KindBreak, 6, 2},
+		{KindElseIf, 7, 1},
+		{KindContinue, 8, 2},
+		{KindRange, 11, 0},
+		{KindTypeSwitch, 12, 1},
+		{KindGoto, 14, 2},
+		{KindSwitch, 17, 0},
+		{KindFallthrough, 19, 1},
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "src.go", test_code, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Decls[0].(*ast.FuncDecl)
+
+	branches := Branches(fset, fn)
+	if len(branches) != len(tests) {
+		t.Fatalf("Branches returned %d branches, want %d: %v\n", len(branches), len(tests), branches)
+	}
+	for i, test := range tests {
+		b := branches[i]
+		if b.Kind != test.kind || b.Pos.Line != test.line || b.Depth != test.depth {
+			t.Errorf("branch %d = %v, want %v on line %d (depth %d)\n", i, b, test.kind, test.line, test.depth)
+		}
+	}
+}
+
+// TestBranchesMatchCount checks that Branches lists exactly what branchCount
+// counts, including the branches of function literals.
+func TestBranchesMatchCount(t *testing.T) {
+	var test_code = `package main
+
+func mixed(x int) {
+	switch x {
+	case 0:
+		fallthrough
+	case 1:
+		for {
+			if x > 0 {
+				break
+			}
+			go func() {
+				for range []int{} {
+					continue
+				}
+			}()
+		}
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "src.go", test_code, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Decls[0].(*ast.FuncDecl)
+	w := &walker{}
+	if n, count := len(Branches(fset, fn)), w.branchCount(fn); uint(n) != count {
+		t.Errorf("Branches returned %d branches, branchCount counted %d\n", n, count)
+	}
+}
@@ -0,0 +1,197 @@
+package branch
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+)
+
+// ChangeKind tells how a function changed between two versions of the code.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeIncreased
+	ChangeDecreased
+)
+
+var changeKindNames = [...]string{
+	ChangeAdded:     "added",
+	ChangeRemoved:   "removed",
+	ChangeIncreased: "increased",
+	ChangeDecreased: "decreased",
+}
+
+// String returns the name of the change, e.g. "increased".
+func (k ChangeKind) String() string {
+	return changeKindNames[k]
+}
+
+// Change is a function that was added, removed, or whose metric changed
+// between a base and a head version of the code.
+type Change struct {
+	Kind ChangeKind
+
+	// ID is the identity of the function the versions were matched by.
+	ID FuncID
+
+	// Base and Head are the function in the two versions. Base is nil for an
+	// added function and Head is nil for a removed one.
+	Base, Head *Func
+
+	// Metric is the metric that was compared, and Delta the difference of its
+	// value in head and in base, clamped to the range of int (the NPath of a
+	// huge function saturates). A missing function counts as 0.
+	Metric Metric
+	Delta  int
+}
+
+// String formats the change for humans and implements the Stringer interface
+// for Change.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: %s: added with %s %d", c.Head.Pos, c.ID, c.Metric.Description(), c.Metric.Value(c.Head))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: %s: removed with %s %d", c.Base.Pos, c.ID, c.Metric.Description(), c.Metric.Value(c.Base))
+	}
+	return fmt.Sprintf("%s: %s: %s %s from %d to %d (%+d)", c.Head.Pos, c.ID, c.Metric.Description(), c.Kind,
+		c.Metric.Value(c.Base), c.Metric.Value(c.Head), c.Delta)
+}
+
+// Worse reports whether the change makes the code worse: a function whose
+// metric increased, or a new function exceeding its limit in l. l may be nil
+// for no limits. Functions with a //branch:ignore directive never make things
+// worse.
+func (c Change) Worse(l *Limits) bool {
+	if c.Head != nil {
+		if d, ok := c.Head.directive(); ok && d.Ignore {
+			return false
+		}
+	}
+	switch c.Kind {
+	case ChangeIncreased:
+		return true
+	case ChangeAdded:
+		if l == nil {
+			return false
+		}
+		limit, ok := l.Limit(c.Head)
+		return ok && c.Metric.Value(c.Head) > limit
+	}
+	return false
+}
+
+// Diff compares the functions of a base and a head version of the code by the
+// metric and returns the changes, sorted by identity. Functions are matched by
+// their qualified identity (see FuncID), so a function that moved to another
+// file of the same package is still the same function. Unchanged functions are
+// not returned.
+//
+// The package paths of the two versions have to agree, which they do for two
+// checkouts of the same module, since paths are derived from go.mod. Diff
+// fails if two functions of a version have the same identity, since they
+// could not be matched.
+func Diff(base, head []*Func, metric Metric) ([]Change, error) {
+	baseFuncs, err := diffKeys(base)
+	if err != nil {
+		return nil, err
+	}
+	headFuncs, err := diffKeys(head)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for key, b := range baseFuncs {
+		h, ok := headFuncs[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, ID: b.ID, Base: b, Metric: metric, Delta: delta(metric.Value(b), 0)})
+			continue
+		}
+		vb, vh := metric.Value(b), metric.Value(h)
+		switch {
+		case vh > vb:
+			changes = append(changes, Change{Kind: ChangeIncreased, ID: h.ID, Base: b, Head: h, Metric: metric, Delta: delta(vb, vh)})
+		case vh < vb:
+			changes = append(changes, Change{Kind: ChangeDecreased, ID: h.ID, Base: b, Head: h, Metric: metric, Delta: delta(vb, vh)})
+		}
+	}
+	for key, h := range headFuncs {
+		if _, ok := baseFuncs[key]; !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, ID: h.ID, Head: h, Metric: metric, Delta: delta(0, metric.Value(h))})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID.String() < changes[j].ID.String()
+	})
+	return changes, nil
+}
+
+// delta returns to - from, clamped to the range of int.
+func delta(from, to uint) int {
+	if to >= from {
+		if to-from > math.MaxInt {
+			return math.MaxInt
+		}
+		return int(to - from)
+	}
+	if from-to > math.MaxInt {
+		return math.MinInt
+	}
+	return -int(from - to)
+}
+
+// diffKeys returns the functions by their matchKey, or an error if two of
+// them have the same key.
+func diffKeys(funcs []*Func) (map[string]*Func, error) {
+	keys, err := uniqueKeys(funcs)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*Func)
+	for i, key := range keys {
+		m[key] = funcs[i]
+	}
+	return m, nil
+}
+
+// uniqueKeys is like matchKeys, but fails if two functions have the same key,
+// e.g. since the same package was analyzed twice.
+func uniqueKeys(funcs []*Func) ([]string, error) {
+	keys := matchKeys(funcs)
+	seen := make(map[string]*Func)
+	for i, key := range keys {
+		if fn := seen[key]; fn != nil {
+			return nil, fmt.Errorf("%v: %v is also declared at %v; functions with the same identity cannot be told apart",
+				funcs[i].Pos, funcs[i].ID, fn.Pos)
+		}
+		seen[key] = funcs[i]
+	}
+	return keys, nil
+}
+
+// matchKeys returns the keys functions are matched by across versions of the
+// code, in the order of funcs. It is the qualified identity, except for init
+// and _ functions, which can only be told apart by their position: they are
+// keyed by the base name of their file and their index among the functions of
+// the same name in that file, so they match even though lines shift and the
+// versions live in different directories.
+func matchKeys(funcs []*Func) []string {
+	keys := make([]string, len(funcs))
+	seen := make(map[string]int)
+	for i, fn := range funcs {
+		id := fn.ID
+		if id.Pos.IsValid() {
+			key := FuncID{Pkg: id.Pkg, Recv: id.Recv, Name: id.Name}.String() + "@" + path.Base(id.Pos.Filename)
+			keys[i] = fmt.Sprintf("%s#%d", key, seen[key])
+			seen[key]++
+			continue
+		}
+		keys[i] = id.String()
+	}
+	return keys
+}
@@ -0,0 +1,167 @@
+package branch
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	base := writeTree(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"p/a.go": `package p
+
+func Same(x int) {
+	if x > 0 {
+	}
+}
+
+func Grows(x int) {
+	if x > 0 {
+	}
+}
+
+func Shrinks(x int) {
+	if x > 0 {
+	}
+	for {
+	}
+}
+
+func Gone() {
+}
+
+func init() {
+}
+`,
+		"p/b.go": `package p
+
+type T struct{}
+
+func (t *T) Moves(x int) {
+	if x > 0 {
+	}
+}
+`,
+	})
+	head := writeTree(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"p/a.go": `package p
+
+func Same(x int) {
+	if x > 0 {
+	}
+}
+
+func Grows(x int) {
+	if x > 0 {
+	}
+	if x < 0 {
+	}
+	switch x {
+	}
+}
+
+func Shrinks(x int) {
+}
+
+func New(x int) {
+	if x > 0 {
+	}
+	if x < 0 {
+	}
+}
+
+func init() {
+}
+`,
+		"p/c.go": `package p
+
+func (t *T) Moves(x int) {
+	if x > 0 {
+	}
+}
+`,
+	})
+
+	analyze := func(root string) []*Func {
+		pkgs, err := (&Config{}).AnalyzeTree(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Funcs(pkgs)
+	}
+	changes, err := Diff(analyze(base), analyze(head), MetricBranchFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		kind  ChangeKind
+		delta int
+		worse bool
+	}
+	want := map[string]change{
+		"example.com/m/p.Grows":   {ChangeIncreased, 2, true},
+		"example.com/m/p.New":     {ChangeAdded, 2, true},
+		"example.com/m/p.Shrinks": {ChangeDecreased, -2, false},
+		"example.com/m/p.Gone":    {ChangeRemoved, 0, false},
+	}
+	limits := &Limits{Metric: MetricBranchFactor, Max: 1}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.ID.String())
+		w, ok := want[c.ID.String()]
+		if !ok {
+			t.Errorf("Diff reported %v\n", c)
+			continue
+		}
+		if c.Kind != w.kind || c.Delta != w.delta {
+			t.Errorf("Diff(%v) = %v %+d, want %v %+d\n", c.ID, c.Kind, c.Delta, w.kind, w.delta)
+		}
+		if worse := c.Worse(limits); worse != w.worse {
+			t.Errorf("Worse(%v) = %v, want %v\n", c.ID, worse, w.worse)
+		}
+	}
+	order := []string{"example.com/m/p.Gone", "example.com/m/p.Grows", "example.com/m/p.New", "example.com/m/p.Shrinks"}
+	if !equalStrings(got, order) {
+		t.Errorf("Diff order = %v, want %v\n", got, order)
+	}
+
+	// a new function within its limit does not make things worse
+	if c := (Change{Kind: ChangeAdded, Head: &Func{BranchFactor: 2}}); c.Worse(nil) {
+		t.Errorf("Worse(added, no limit) = true, want false\n")
+	}
+
+	// nor does an ignored one
+	ignored := &Func{BranchFactor: 9, Directives: []Directive{{Text: "branch:ignore legacy", Ignore: true}}}
+	for _, c := range []Change{{Kind: ChangeIncreased, Base: &Func{}, Head: ignored}, {Kind: ChangeAdded, Head: ignored}} {
+		if c.Worse(limits) {
+			t.Errorf("Worse(%v, ignored) = true, want false\n", c.Kind)
+		}
+	}
+
+	// functions with the same identity cannot be matched
+	dup := []*Func{{ID: FuncID{Pkg: "main", Name: "main"}}, {ID: FuncID{Pkg: "main", Name: "main"}}}
+	if _, err := Diff(dup, nil, MetricBranchFactor); err == nil {
+		t.Errorf("Diff(duplicate functions) succeeded, want an error\n")
+	}
+
+	// the saturated NPath does not overflow Delta
+	huge := []*Func{{ID: FuncID{Pkg: "m", Name: "F"}, NPath: new(big.Int).Lsh(big.NewInt(1), 100)}}
+	small := []*Func{{ID: FuncID{Pkg: "m", Name: "F"}, NPath: big.NewInt(1)}}
+	for _, test := range []struct {
+		base, head []*Func
+		delta      int
+	}{
+		{small, huge, math.MaxInt},
+		{huge, small, math.MinInt},
+		{nil, huge, math.MaxInt},
+		{huge, nil, math.MinInt},
+	} {
+		changes, err := Diff(test.base, test.head, MetricNPath)
+		if err != nil || len(changes) != 1 || changes[0].Delta != test.delta {
+			t.Errorf("Diff(NPath) = %v, %v, want a delta of %d\n", changes, err, test.delta)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package branch
+
+import (
+	"bufio"
+	"context"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config controls how source files are selected and analyzed. The zero value
+// analyzes non-test files for the current platform.
+type Config struct {
+	// Tests includes _test.go files (and external _test packages).
+	Tests bool
+
+	// Build is the build context whose constraints (GOOS, GOARCH, build
+	// tags) decide which files are part of a package. If nil, build.Default
+	// is used.
+	Build *build.Context
+
+	// FuncLits reports every function literal as a function of its own,
+	// named after the function it is in like the compiler does, e.g.
+	// "Outer.func1" or "Outer.func1.2" for a literal nested in Outer.func1.
+	FuncLits bool
+
+	// ExcludeFuncLits leaves the branches of function literals out of the
+	// counts of the function they are in.
+	ExcludeFuncLits bool
+
+	// Policy decides which statements count towards the branch factor. If
+	// nil, DefaultPolicy is used.
+	Policy *Policy
+
+	// ExcludeConstant leaves if, for and switch statements whose condition
+	// is a compile-time constant out of the branch factor. They are reported
+	// in Func.ConstConds either way.
+	ExcludeConstant bool
+
+	// Importer imports the packages the analyzed code imports, so their
+	// constants can be evaluated (e.g. importer.Default()). If nil, imports
+	// are not followed and only the constants of the package itself are
+	// known.
+	Importer types.Importer
+
+	// Maintainability is the formula of the maintainability index of
+	// functions and files, see MIVariant.
+	Maintainability MIVariant
+
+	// Cache, if not nil, stores the results of analyzed directories, so
+	// unchanged directories are not analyzed again, see Cache.
+	Cache *Cache
+
+	// Workers is the largest number of goroutines AnalyzeTree parses and
+	// analyzes files with at the same time. If zero, runtime.GOMAXPROCS(0)
+	// is used.
+	Workers int
+}
+
+// Totals aggregates the results of several functions.
+type Totals struct {
+	// Funcs is the number of functions. Function literals reported on their
+	// own (Config.FuncLits) are only counted, like all their other results,
+	// if Config.ExcludeFuncLits keeps them out of the function they are in.
+	Funcs int
+
+	// BranchFactor, Cyclomatic, Cognitive and Concurrency are the sums over
+	// all functions.
+	BranchFactor uint
+	Cyclomatic   uint
+	Cognitive    uint
+	Concurrency  uint
+
+	// MaxBranchFactor is the largest branch factor of a single function.
+	MaxBranchFactor uint
+
+	// MaxNesting is the deepest nesting of a single function.
+	MaxNesting int
+
+	// HalsteadVolume is the sum of the Halstead volumes of all functions.
+	HalsteadVolume float64
+}
+
+// add adds the results of fn to the totals.
+func (t *Totals) add(fn *Func) {
+	t.Funcs++
+	t.BranchFactor += fn.BranchFactor
+	t.Cyclomatic += fn.Cyclomatic
+	t.Cognitive += fn.Cognitive
+	t.Concurrency += fn.Concurrency
+	t.HalsteadVolume += fn.Halstead.Volume
+	if fn.BranchFactor > t.MaxBranchFactor {
+		t.MaxBranchFactor = fn.BranchFactor
+	}
+	if fn.Nesting.Max > t.MaxNesting {
+		t.MaxNesting = fn.Nesting.Max
+	}
+}
+
+// merge adds other to the totals.
+func (t *Totals) merge(other Totals) {
+	t.Funcs += other.Funcs
+	t.BranchFactor += other.BranchFactor
+	t.Cyclomatic += other.Cyclomatic
+	t.Cognitive += other.Cognitive
+	t.Concurrency += other.Concurrency
+	t.HalsteadVolume += other.HalsteadVolume
+	if other.MaxBranchFactor > t.MaxBranchFactor {
+		t.MaxBranchFactor = other.MaxBranchFactor
+	}
+	if other.MaxNesting > t.MaxNesting {
+		t.MaxNesting = other.MaxNesting
+	}
+}
+
+// File is the result of analyzing a single file.
+type File struct {
+	// Name is the path of the file.
+	Name string
+
+	// Funcs holds the result of every function in the file, in source order.
+	Funcs []*Func
+
+	// Lines counts the lines of the whole file.
+	Lines Lines
+
+	// Maintainability is the maintainability index of the file, computed
+	// from the totals of its functions and its lines, see MIVariant.
+	Maintainability float64
+
+	Totals
+}
+
+// Package is the result of analyzing the files of one package in a single
+// directory.
+type Package struct {
+	// Path is the import path of the package if the directory is inside a
+	// module. Otherwise it is the slash-separated directory relative to the
+	// working directory (absolute if it is not below it), so it is the same
+	// however the directory was named and reached. External test packages
+	// have a "_test" suffix.
+	Path string
+
+	// Name is the package name.
+	Name string
+
+	// Dir is the directory of the package.
+	Dir string
+
+	// Files are the analyzed files of the package, sorted by name.
+	Files []*File
+
+	Totals
+}
+
+// config returns c, or the default configuration if c is nil.
+func (c *Config) config() *Config {
+	if c == nil {
+		return &Config{}
+	}
+	return c
+}
+
+// policy returns the counting policy of the configuration.
+func (c *Config) policy() *Policy {
+	if c.Policy != nil {
+		return c.Policy
+	}
+	return DefaultPolicy
+}
+
+// buildContext returns the build context of the configuration.
+func (c *Config) buildContext() *build.Context {
+	if c.Build != nil {
+		return c.Build
+	}
+	return &build.Default
+}
+
+// AnalyzeDir analyzes the Go packages in directory dir (but not in its
+// subdirectories). Syntax errors are reported in the returned
+// scanner.ErrorList; the packages still hold every function that could be
+// parsed. The files are parsed in parallel, see AnalyzeDirContext.
+func (c *Config) AnalyzeDir(dir string) ([]*Package, error) {
+	return c.AnalyzeDirContext(context.Background(), dir)
+}
+
+// AnalyzeTree analyzes the Go packages in root and, recursively, in all of its
+// subdirectories. As with the go command, vendor and testdata directories and
+// directories whose name starts with "." or "_" are skipped. Errors are
+// reported as in AnalyzeDir. The directories are analyzed in parallel, see
+// AnalyzeTreeContext.
+func (c *Config) AnalyzeTree(root string) ([]*Package, error) {
+	return c.AnalyzeTreeContext(context.Background(), root)
+}
+
+// AnalyzeFiles analyzes the given Go files. The files are grouped into
+// packages by directory and package name; build constraints and the Tests
+// setting are not applied, since the files were named explicitly. The files
+// are analyzed in parallel, see AnalyzeFilesContext.
+func (c *Config) AnalyzeFiles(filenames ...string) ([]*Package, error) {
+	return c.AnalyzeFilesContext(context.Background(), filenames...)
+}
+
+// skipDir reports whether the directory called name is skipped by AnalyzeTree.
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" ||
+		strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// goFiles returns the names of the Go files in dir that belong to the build
+// described by the configuration.
+func (c *Config) goFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctxt := c.buildContext()
+
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if !c.Tests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		// MatchFile checks the file name (GOOS/GOARCH suffixes) and the
+		// build constraints in the file
+		if ok, err := ctxt.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// analyzePackages parses the named files in dir and groups their results by
+// package. Syntax errors are appended to errs.
+// The files are parsed in parallel as far as p allows (p may be nil); if the
+// context of p is done, analyzePackages stops early and returns nothing.
+func (c *Config) analyzePackages(p *pool, dir string, names []string, errs *scanner.ErrorList) []*Package {
+	fset := token.NewFileSet()
+	importPath := dirImportPath(dir)
+	byName := make(map[string]*Package)
+	var pkgs []*Package
+	files := make(map[*Package][]*ast.File)
+
+	sort.Strings(names)
+	srcs := make([][]byte, len(names))
+	readErr := false
+	for i, name := range names {
+		if p.err() != nil {
+			return nil
+		}
+		src, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			errs.Add(token.Position{Filename: filepath.Join(dir, name)}, err.Error())
+			readErr = true
+		}
+		srcs[i] = src
+	}
+	key := ""
+	if c.Cache != nil && c.Importer == nil && !readErr {
+		key = c.cacheKey(dir, importPath, names, srcs)
+		if pkgs, ok := c.Cache.get(key, dir, len(names)); ok {
+			// the totals are not stored, since File.Funcs hides
+			// Totals.Funcs in JSON
+			for _, pkg := range pkgs {
+				pkg.Totals = Totals{}
+				for _, file := range pkg.Files {
+					file.Totals = c.totals(file.Funcs)
+					pkg.merge(file.Totals)
+				}
+			}
+			return pkgs
+		}
+	}
+	numErrs := len(*errs)
+
+	// the files are parsed in any order, but combined in the order of
+	// their names
+	parsed := make([]*ast.File, len(names))
+	parseErrs := make([]error, len(names))
+	p.do(len(names), func(i int) {
+		if srcs[i] != nil { // nil if it could not be read
+			filename := filepath.Join(dir, names[i])
+			parsed[i], parseErrs[i] = parser.ParseFile(fset, filename, srcs[i], parser.AllErrors|parser.ParseComments)
+		}
+	})
+	if p.err() != nil {
+		return nil
+	}
+
+	for i, f := range parsed {
+		if err := parseErrs[i]; err != nil {
+			if list, ok := err.(scanner.ErrorList); ok {
+				*errs = append(*errs, list...)
+			} else {
+				errs.Add(token.Position{Filename: filepath.Join(dir, names[i])}, err.Error())
+			}
+		}
+		if f == nil || f.Name == nil {
+			continue
+		}
+
+		pkg := byName[f.Name.Name]
+		if pkg == nil {
+			pkg = &Package{Path: importPath, Name: f.Name.Name, Dir: dir}
+			if strings.HasSuffix(pkg.Name, "_test") {
+				pkg.Path += "_test"
+			}
+			byName[pkg.Name] = pkg
+			pkgs = append(pkgs, pkg)
+		}
+		files[pkg] = append(files[pkg], f)
+	}
+
+	// the files of a package are type-checked together, so constants
+	// declared in one file are known in the others
+	for _, pkg := range pkgs {
+		if p.err() != nil {
+			return nil
+		}
+		c.analyzeSyntax(pkg, fset, files[pkg], nil)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Path < pkgs[j].Path })
+	if key != "" && len(*errs) == numErrs {
+		c.Cache.put(key, dir, pkgs)
+	}
+	return pkgs
+}
+
+// AnalyzeSyntax analyzes the parsed files of the package with the import path
+// path, for tools that already loaded the package, such as go/analysis passes.
+// info is the type information of the files; if it is nil, the files are
+// type-checked on their own. The files should be parsed with comments, or
+// the directives of the functions are lost.
+func (c *Config) AnalyzeSyntax(fset *token.FileSet, path string, files []*ast.File, info *types.Info) *Package {
+	c = c.config()
+	pkg := &Package{Path: path}
+	if len(files) > 0 {
+		pkg.Name = files[0].Name.Name
+		pkg.Dir = filepath.Dir(fset.Position(files[0].Package).Filename)
+	}
+	c.analyzeSyntax(pkg, fset, files, info)
+	return pkg
+}
+
+// analyzeSyntax adds the results of files to pkg.
+func (c *Config) analyzeSyntax(pkg *Package, fset *token.FileSet, files []*ast.File, info *types.Info) {
+	if info == nil {
+		info = c.typeCheck(fset, pkg.Path, files)
+	}
+	for _, f := range files {
+		filename := fset.Position(f.Package).Filename
+		funcs := c.analyzeFile(fset, pkg.Path, f, info)
+		file := &File{Name: filename, Funcs: funcs, Totals: c.totals(funcs)}
+		file.Lines = fileLines(fset, f)
+		file.Maintainability = c.Maintainability.Index(file.HalsteadVolume, file.BranchFactor, file.Lines)
+		pkg.Files = append(pkg.Files, file)
+	}
+	for _, file := range pkg.Files {
+		pkg.merge(file.Totals)
+	}
+}
+
+// totals returns the totals of funcs. Function literals reported on their own
+// (see Config.FuncLits) are left out unless c.ExcludeFuncLits is set, since
+// their branches are already counted in the function they are in.
+func (c *Config) totals(funcs []*Func) Totals {
+	var t Totals
+	for _, fn := range funcs {
+		if fn.Lit && !c.ExcludeFuncLits {
+			continue
+		}
+		t.add(fn)
+	}
+	return t
+}
+
+// dirImportPath returns the import path of the package in dir, which is
+// derived from the closest go.mod file above it. If there is none, it is the
+// slash-separated path of dir relative to the working directory, or the
+// absolute path if dir is not below it, so it neither depends on how dir was
+// spelled nor on the entry point that analyzed it. The working directory
+// itself is called by its base name.
+func dirImportPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(dir))
+	}
+	for d := abs; ; {
+		if mod := modulePath(filepath.Join(d, "go.mod")); mod != "" {
+			rel, err := filepath.Rel(d, abs)
+			if err != nil {
+				break
+			}
+			return path.Join(mod, filepath.ToSlash(rel))
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	rel, err := filepath.Rel(wd, abs)
+	switch {
+	case err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)):
+		return filepath.ToSlash(abs)
+	case rel == ".":
+		return filepath.Base(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// modulePath returns the module path declared in the go.mod file gomod, or ""
+// if it cannot be read.
+func modulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "module") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		}
+	}
+	return ""
+}
+
+// Funcs returns the functions of all files of pkgs, in order.
+func Funcs(pkgs []*Package) []*Func {
+	var funcs []*Func
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			funcs = append(funcs, file.Funcs...)
+		}
+	}
+	return funcs
+}
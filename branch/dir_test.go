@@ -0,0 +1,259 @@
+package branch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates the files (path -> content) below a new temporary
+// directory and returns the directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var testTree = map[string]string{
+	"go.mod": "module example.com/m\n",
+	"a.go": `package a
+
+func A(x int) {
+	if x > 0 {
+	}
+	for {
+	}
+}
+`,
+	"a_test.go": `package a
+
+func testHelper() {
+	if true {
+	}
+}
+`,
+	"a_ext_test.go": `package a_test
+
+func TestExt() {
+	switch {
+	}
+}
+`,
+	"ignored.go": `//go:build ignore
+
+package main
+
+func main() {
+	if true {
+	}
+}
+`,
+	"a_plan9.go": `package a
+
+func onlyPlan9() {}
+`,
+	"sub/b.go": `package b
+
+func (b *B) M() {
+	for range b.xs {
+		if b == nil {
+			continue
+		}
+	}
+}
+
+type B struct{ xs []int }
+`,
+	"vendor/v/v.go": "package v\n\nfunc V() {}\n",
+	"testdata/t.go": "package t\n\nfunc T() {}\n",
+	"_skip/s.go":    "package s\n\nfunc S() {}\n",
+}
+
+func TestAnalyzeTree(t *testing.T) {
+	root := writeTree(t, testTree)
+
+	tests := []struct {
+		tests bool
+		pkgs  []string
+		funcs []string
+	}{
+		{false,
+			[]string{"example.com/m", "example.com/m/sub"},
+			[]string{"example.com/m.A", "example.com/m/sub.(*B).M"}},
+		{true,
+			[]string{"example.com/m", "example.com/m/sub", "example.com/m_test"},
+			[]string{"example.com/m.A", "example.com/m.testHelper",
+				"example.com/m/sub.(*B).M", "example.com/m_test.TestExt"}},
+	}
+
+	for _, test := range tests {
+		cfg := &Config{Tests: test.tests}
+		pkgs, err := cfg.AnalyzeTree(root)
+		if err != nil {
+			t.Fatalf("AnalyzeTree returned error %v\n", err)
+		}
+
+		var paths, funcs []string
+		for _, pkg := range pkgs {
+			paths = append(paths, pkg.Path)
+			for _, file := range pkg.Files {
+				for _, fn := range file.Funcs {
+					funcs = append(funcs, fn.ID.String())
+				}
+			}
+		}
+		if !equalStrings(paths, test.pkgs) {
+			t.Errorf("AnalyzeTree(Tests: %v) packages = %v, want %v\n", test.tests, paths, test.pkgs)
+		}
+		if !equalStrings(funcs, test.funcs) {
+			t.Errorf("AnalyzeTree(Tests: %v) functions = %v, want %v\n", test.tests, funcs, test.funcs)
+		}
+	}
+}
+
+func TestAnalyzeTreeTotals(t *testing.T) {
+	root := writeTree(t, testTree)
+
+	pkgs, err := (&Config{Tests: true}).AnalyzeTree(root)
+	if err != nil {
+		t.Fatalf("AnalyzeTree returned error %v\n", err)
+	}
+
+	tests := []struct {
+		path         string
+		files        int
+		funcs        int
+		branchFactor uint
+		max          uint
+	}{
+		{"example.com/m", 2, 2, 3, 2},
+		{"example.com/m/sub", 1, 1, 3, 3},
+		{"example.com/m_test", 1, 1, 1, 1},
+	}
+	for i, test := range tests {
+		pkg := pkgs[i]
+		if pkg.Path != test.path || len(pkg.Files) != test.files || pkg.Funcs != test.funcs ||
+			pkg.BranchFactor != test.branchFactor || pkg.MaxBranchFactor != test.max {
+			t.Errorf("package %d = {%v, %d files, %d funcs, total %d, max %d}, want {%v, %d files, %d funcs, total %d, max %d}\n",
+				i, pkg.Path, len(pkg.Files), pkg.Funcs, pkg.BranchFactor, pkg.MaxBranchFactor,
+				test.path, test.files, test.funcs, test.branchFactor, test.max)
+		}
+	}
+
+	file := pkgs[0].Files[0]
+	if filepath.Base(file.Name) != "a.go" || file.BranchFactor != 2 {
+		t.Errorf("file %v has branch factor %d, want a.go with 2\n", file.Name, file.BranchFactor)
+	}
+}
+
+func TestAnalyzeNoModule(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a/a.go":        "package a\n\nfunc A() {}\n",
+		"a/a_test.go":   "package a_test\n\nfunc TestA() {}\n",
+		"a/sub/b.go":    "package b\n\nfunc B() {}\n",
+		"a/sub/b2.go":   "package b\n\nfunc B2() {}\n",
+		"cmd/a/main.go": "package main\n\nfunc main() {}\n",
+		"cmd/b/main.go": "package main\n\nfunc main() {}\n",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	funcs := func(pkgs []*Package) []string {
+		var ids []string
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				for _, fn := range file.Funcs {
+					ids = append(ids, fn.ID.String())
+				}
+			}
+		}
+		return ids
+	}
+
+	// the identities depend neither on how the directory is spelled nor on
+	// how it is reached
+	cfg := &Config{Tests: true}
+	want := []string{"a.A", "a/sub.B", "a/sub.B2", "a_test.TestA"}
+	for _, dir := range []string{"a", "./a", "a/", "a/../a", filepath.Join(root, "a")} {
+		pkgs, err := cfg.AnalyzeTree(dir)
+		if err != nil {
+			t.Fatalf("AnalyzeTree(%v) returned error %v\n", dir, err)
+		}
+		if got := funcs(pkgs); !equalStrings(got, want) {
+			t.Errorf("AnalyzeTree(%v) functions = %v, want %v\n", dir, got, want)
+		}
+	}
+
+	for _, dir := range []string{"a/sub", "./a/sub/", filepath.Join(root, "a", "sub")} {
+		pkgs, err := cfg.AnalyzeDir(dir)
+		if err != nil {
+			t.Fatalf("AnalyzeDir(%v) returned error %v\n", dir, err)
+		}
+		if got, want := funcs(pkgs), []string{"a/sub.B", "a/sub.B2"}; !equalStrings(got, want) {
+			t.Errorf("AnalyzeDir(%v) functions = %v, want %v\n", dir, got, want)
+		}
+	}
+
+	// packages with the same name in different directories stay apart
+	pkgs, err := cfg.AnalyzeFiles("cmd/a/main.go", "./cmd/b/main.go")
+	if err != nil {
+		t.Fatalf("AnalyzeFiles returned error %v\n", err)
+	}
+	if got, want := funcs(pkgs), []string{"cmd/a.main", "cmd/b.main"}; !equalStrings(got, want) {
+		t.Errorf("AnalyzeFiles(cmd/a/main.go, cmd/b/main.go) functions = %v, want %v\n", got, want)
+	}
+
+	// the working directory itself is called by its name
+	if err := os.Chdir("a"); err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err = cfg.AnalyzeDir(".")
+	if err != nil {
+		t.Fatalf("AnalyzeDir(.) returned error %v\n", err)
+	}
+	if got, want := funcs(pkgs), []string{"a.A", "a_test.TestA"}; !equalStrings(got, want) {
+		t.Errorf("AnalyzeDir(.) functions = %v, want %v\n", got, want)
+	}
+}
+
+func TestAnalyzeDirErrors(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"good.go": "package p\n\nfunc Good() {\n\tif true {\n\t}\n}\n",
+		"bad.go":  "package p\n\nfunc Bad() {\n\tx := )\n}\n",
+	})
+
+	pkgs, err := (*Config)(nil).AnalyzeDir(root)
+	if err == nil {
+		t.Fatalf("AnalyzeDir did not return an error, but should\n")
+	}
+	if pkgs == nil || pkgs[0].Funcs != 2 {
+		t.Errorf("AnalyzeDir did not report the functions of the package\n")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
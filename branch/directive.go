@@ -0,0 +1,131 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// directivePrefix starts a directive comment. Like other Go directives
+// (//go:generate, //nolint) there is no space after the slashes.
+const directivePrefix = "//branch:"
+
+// Directive is a comment in the doc comment of a function that changes how its
+// limit is checked:
+//
+//	//branch:ignore reason
+//	//branch:max=25 reason
+//
+// ignore exempts the function from every limit; max replaces the branch
+// factor limit of the function. The reason is free text that should explain
+// why the function has to be this complex.
+type Directive struct {
+	// Pos is the position of the comment.
+	Pos token.Position
+
+	// Text is the comment without the leading //.
+	Text string
+
+	Ignore bool
+	Max    uint
+	Reason string
+
+	// Err describes what is wrong with a malformed directive, which is not
+	// honored. It is empty for a valid directive.
+	Err string
+}
+
+// parseDirectives returns the directives in the doc comment doc, which may be
+// nil.
+func parseDirectives(fset *token.FileSet, doc *ast.CommentGroup) []Directive {
+	if doc == nil {
+		return nil
+	}
+	var list []Directive
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, directivePrefix) {
+			continue
+		}
+		d := Directive{Pos: fset.Position(c.Pos()), Text: strings.TrimPrefix(c.Text, "//")}
+		fields := strings.Fields(strings.TrimPrefix(c.Text, directivePrefix))
+		if len(fields) == 0 {
+			d.Err = "empty directive"
+			list = append(list, d)
+			continue
+		}
+		d.Reason = strings.Join(fields[1:], " ")
+		switch verb := fields[0]; {
+		case verb == "ignore":
+			d.Ignore = true
+		case strings.HasPrefix(verb, "max="):
+			max, err := strconv.ParseUint(strings.TrimPrefix(verb, "max="), 10, 0)
+			if err != nil || max == 0 {
+				d.Err = fmt.Sprintf("bad limit in %q", verb)
+			}
+			d.Max = uint(max)
+		default:
+			d.Err = fmt.Sprintf("unknown directive %q", verb)
+		}
+		list = append(list, d)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i].Err == "" {
+			list[i].Err = "more than one directive, only the first one is honored"
+		}
+	}
+	return list
+}
+
+// directive returns the directive honored for fn, if any.
+func (fn *Func) directive() (Directive, bool) {
+	if len(fn.Directives) == 0 || fn.Directives[0].Err != "" {
+		return Directive{}, false
+	}
+	return fn.Directives[0], true
+}
+
+// Warning is a problem with a directive: it is malformed, has no reason or is
+// no longer needed.
+type Warning struct {
+	Func      *Func
+	Directive Directive
+	Msg       string
+}
+
+// String formats the warning for humans and implements the Stringer interface
+// for Warning.
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s: warning: //%s: %s", w.Directive.Pos, w.Func.ID, w.Directive.Text, w.Msg)
+}
+
+// Warnings returns the problems with the directives of funcs, in the order
+// given. A directive is no longer needed if the function is within the limit
+// it would have without it. Whether a max directive is needed can only be told
+// when the branch factor is limited.
+func (l *Limits) Warnings(funcs []*Func) []Warning {
+	var warnings []Warning
+	for _, fn := range funcs {
+		for i, d := range fn.Directives {
+			warn := func(format string, args ...interface{}) {
+				warnings = append(warnings, Warning{Func: fn, Directive: d, Msg: fmt.Sprintf(format, args...)})
+			}
+			switch {
+			case d.Err != "":
+				warn("%s", d.Err)
+				continue
+			case d.Reason == "":
+				warn("directive has no reason")
+			}
+			if i > 0 || (d.Max > 0 && l.Metric != MetricBranchFactor) {
+				continue
+			}
+			limit, ok := l.limit(fn)
+			if value := l.Metric.Value(fn); ok && value <= limit {
+				warn("no longer needed, %s %d is within the limit %d", l.Metric.Description(), value, limit)
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,120 @@
+package branch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectives(t *testing.T) {
+	var test_code = `package main
+
+// Parse is complex on purpose.
+//
+//branch:max=4 mirrors the grammar
+func Parse(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+}
+
+//branch:ignore generated state machine
+func Generated(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+	if x > 3 {
+	}
+}
+
+//branch:max=10
+func NoReason(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+}
+
+//branch:ignore was complex once
+func Simple() {
+}
+
+//branch:max=lots because
+func Malformed(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+}
+
+//branch:ignore first
+//branch:ignore second
+func Twice() {
+}
+
+//branch:max=5 the limit is high enough already
+func Grown(x int) {
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+	if x > 3 {
+	}
+	if x > 4 {
+	}
+	if x > 5 {
+	}
+}
+`
+	funcs, err := AnalyzeSource("src.go", test_code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := &Limits{Metric: MetricBranchFactor, Max: 2}
+
+	var got []string
+	for _, v := range limits.Check(funcs) {
+		got = append(got, v.Func.ID.Name)
+	}
+	want := []string{"Malformed", "Grown"}
+	if !equalStrings(got, want) {
+		t.Errorf("Check() = %v, want %v\n", got, want)
+	}
+
+	wantWarnings := []string{
+		"src.go:27:1: main.NoReason: warning: //branch:max=10: directive has no reason",
+		"src.go:37:1: main.Simple: warning: //branch:ignore was complex once: no longer needed, branch factor 0 is within the limit 2",
+		`src.go:41:1: main.Malformed: warning: //branch:max=lots because: bad limit in "max=lots"`,
+		"src.go:51:1: main.Twice: warning: //branch:ignore first: no longer needed, branch factor 0 is within the limit 2",
+		"src.go:52:1: main.Twice: warning: //branch:ignore second: more than one directive, only the first one is honored",
+	}
+	got = nil
+	for _, w := range limits.Warnings(funcs) {
+		got = append(got, w.String())
+	}
+	if !equalStrings(got, wantWarnings) {
+		t.Errorf("Warnings() =\n%s\nwant\n%s\n", strings.Join(got, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+
+	// ignore is honored for every metric, max only for the branch factor
+	limits = &Limits{Metric: MetricCyclomatic, Max: 2}
+	got = nil
+	for _, v := range limits.Check(funcs) {
+		got = append(got, v.Func.ID.Name)
+	}
+	want = []string{"Parse", "NoReason", "Malformed", "Grown"}
+	if !equalStrings(got, want) {
+		t.Errorf("Check(cyclomatic) = %v, want %v\n", got, want)
+	}
+}
@@ -0,0 +1,445 @@
+package branch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Report is the result of an analysis in the form the Write methods
+// serialize it: the analyzed functions and the functions exceeding their
+// limits.
+type Report struct {
+	Funcs      []*Func
+	Violations []Violation
+
+	// Warnings are the problems with the //branch: directives of Funcs.
+	Warnings []Warning
+
+	// Changes are the differences to a base version of the code, if the
+	// report is the result of a Diff.
+	Changes []Change
+
+	// Policy is the counting policy the branch factors were computed with.
+	// If nil, DefaultPolicy is reported.
+	Policy *Policy
+
+	// Files are the analyzed files, for their maintainability index. They
+	// may be left out.
+	Files []*File
+
+	// Maintainability is the formula the maintainability indexes were
+	// computed with.
+	Maintainability MIVariant
+}
+
+// policy returns the counting policy of the report.
+func (r *Report) policy() *Policy {
+	if r.Policy != nil {
+		return r.Policy
+	}
+	return DefaultPolicy
+}
+
+// record is the flat form of a Func used by the JSON and CSV formats.
+type record struct {
+	Package      string        `json:"package"`
+	Function     string        `json:"function"`
+	File         string        `json:"file"`
+	Line         int           `json:"line"`
+	Column       int           `json:"column"`
+	BranchFactor uint          `json:"branch_factor"`
+	Kinds        map[Kind]uint `json:"kinds"`
+	Cyclomatic   uint          `json:"cyclomatic"`
+	Cognitive    uint          `json:"cognitive"`
+	Concurrency  uint          `json:"concurrency"`
+	NPath        *big.Int      `json:"npath"`
+	Halstead     halstead      `json:"halstead"`
+	Lines        lines         `json:"lines"`
+	MI           float64       `json:"maintainability"`
+	MaxDepth     int           `json:"max_depth"`
+	DeepestLine  int           `json:"deepest_line,omitempty"`
+	DepthProfile []uint        `json:"depth_profile"`
+	Unreachable  []int         `json:"unreachable_lines,omitempty"`
+	ConstConds   []int         `json:"constant_condition_lines,omitempty"`
+}
+
+// halstead is the part of Halstead in record.
+type halstead struct {
+	Volume     float64 `json:"volume"`
+	Difficulty float64 `json:"difficulty"`
+	Effort     float64 `json:"effort"`
+}
+
+// lines is the JSON form of Lines.
+type lines struct {
+	Physical int `json:"physical"`
+	Logical  int `json:"logical,omitempty"`
+	Comment  int `json:"comment"`
+}
+
+// fileRecord is the JSON form of a File.
+type fileRecord struct {
+	File            string  `json:"file"`
+	Functions       int     `json:"functions"`
+	BranchFactor    uint    `json:"branch_factor"`
+	HalsteadVolume  float64 `json:"halstead_volume"`
+	Lines           lines   `json:"lines"`
+	Maintainability float64 `json:"maintainability"`
+}
+
+// miRecord documents the formula of the maintainability index in the output.
+type miRecord struct {
+	Variant MIVariant `json:"variant"`
+	Formula string    `json:"formula"`
+}
+
+// csvHeader returns the column names of the CSV format: the fields of record,
+// with one column per kind of branching statement in place of Kinds, without
+// the details of the nesting, and with the name of the policy.
+func csvHeader() []string {
+	header := []string{"package", "function", "file", "line", "column", "branch_factor"}
+	for _, name := range kindNames {
+		header = append(header, name)
+	}
+	return append(header, "cyclomatic", "cognitive", "concurrency", "npath",
+		"halstead_volume", "halstead_difficulty", "halstead_effort",
+		"physical_lines", "logical_lines", "comment_lines", "maintainability", "max_depth", "policy", "mi_variant")
+}
+
+func newRecord(fn *Func) record {
+	return record{
+		Package:      fn.ID.Pkg,
+		Function:     fn.ID.Local(),
+		File:         filepath.ToSlash(fn.Pos.Filename),
+		Line:         fn.Pos.Line,
+		Column:       fn.Pos.Column,
+		BranchFactor: fn.BranchFactor,
+		Kinds:        fn.Breakdown.Kinds,
+		Cyclomatic:   fn.Cyclomatic,
+		Cognitive:    fn.Cognitive,
+		Concurrency:  fn.Concurrency,
+		NPath:        fn.NPath,
+		Halstead:     halstead{fn.Halstead.Volume, fn.Halstead.Difficulty, fn.Halstead.Effort},
+		Lines:        lines{fn.Lines.Physical, fn.Lines.Logical, fn.Lines.Comment},
+		MI:           fn.Maintainability,
+		MaxDepth:     fn.Nesting.Max,
+		DeepestLine:  fn.Nesting.Deepest.Line,
+		DepthProfile: fn.Nesting.Profile,
+		Unreachable:  lineNumbers(fn.Unreachable),
+		ConstConds:   constCondLines(fn.ConstConds),
+	}
+}
+
+func (r record) csv(policy *Policy, variant MIVariant) []string {
+	row := []string{
+		r.Package,
+		r.Function,
+		r.File,
+		strconv.Itoa(r.Line),
+		strconv.Itoa(r.Column),
+		formatUint(r.BranchFactor),
+	}
+	for kind := range kindNames {
+		row = append(row, formatUint(r.Kinds[Kind(kind)]))
+	}
+	return append(row, formatUint(r.Cyclomatic), formatUint(r.Cognitive), formatUint(r.Concurrency), r.NPath.String(),
+		formatFloat(r.Halstead.Volume), formatFloat(r.Halstead.Difficulty), formatFloat(r.Halstead.Effort),
+		strconv.Itoa(r.Lines.Physical), strconv.Itoa(r.Lines.Logical), strconv.Itoa(r.Lines.Comment), formatFloat(r.MI),
+		strconv.Itoa(r.MaxDepth), policy.Name, variant.String())
+}
+
+// lineNumbers returns the line numbers of positions.
+func lineNumbers(positions []token.Position) []int {
+	var list []int
+	for _, pos := range positions {
+		list = append(list, pos.Line)
+	}
+	return list
+}
+
+// constCondLines returns the line numbers of conds.
+func constCondLines(conds []ConstCond) []int {
+	var list []int
+	for _, c := range conds {
+		list = append(list, c.Pos.Line)
+	}
+	return list
+}
+
+func formatUint(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+// formatFloat formats x with two decimals, which is plenty for the derived
+// metrics.
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', 2, 64)
+}
+
+// violationRecord is the JSON form of a Violation.
+type violationRecord struct {
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Metric   string `json:"metric"`
+	Value    uint   `json:"value"`
+	Limit    uint   `json:"limit"`
+}
+
+// warningRecord is the JSON form of a Warning.
+type warningRecord struct {
+	Package   string `json:"package"`
+	Function  string `json:"function"`
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Directive string `json:"directive"`
+	Message   string `json:"message"`
+}
+
+// changeRecord is the JSON form of a Change.
+type changeRecord struct {
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Change   string `json:"change"`
+	Metric   string `json:"metric"`
+	Base     uint   `json:"base"`
+	Head     uint   `json:"head"`
+	Delta    int    `json:"delta"`
+}
+
+func newChangeRecord(c Change) changeRecord {
+	r := changeRecord{
+		Package:  c.ID.Pkg,
+		Function: c.ID.Local(),
+		Change:   c.Kind.String(),
+		Metric:   c.Metric.String(),
+		Delta:    c.Delta,
+	}
+	pos := c.Head
+	if c.Base != nil {
+		r.Base = c.Metric.Value(c.Base)
+		pos = c.Base
+	}
+	if c.Head != nil {
+		r.Head = c.Metric.Value(c.Head)
+		pos = c.Head
+	}
+	r.File = filepath.ToSlash(pos.Pos.Filename)
+	r.Line = pos.Pos.Line
+	return r
+}
+
+// WriteJSON writes the report as a JSON object with the "policy" and a
+// "functions" and a "violations" array, one object with file, line, function
+// and metrics per entry, with the formula of the maintainability index in
+// "maintainability" and the analyzed files in "files" if there are any. The
+// warnings about directives and the changes of a diff follow in a "warnings"
+// and a "changes" array if there are any.
+func (r *Report) WriteJSON(w io.Writer) error {
+	out := struct {
+		Policy     *Policy           `json:"policy"`
+		MI         miRecord          `json:"maintainability"`
+		Files      []fileRecord      `json:"files,omitempty"`
+		Functions  []record          `json:"functions"`
+		Violations []violationRecord `json:"violations"`
+		Warnings   []warningRecord   `json:"warnings,omitempty"`
+		Changes    []changeRecord    `json:"changes,omitempty"`
+	}{
+		Policy:     r.policy(),
+		MI:         miRecord{r.Maintainability, r.Maintainability.Formula()},
+		Functions:  []record{},
+		Violations: []violationRecord{},
+	}
+	for _, f := range r.Files {
+		out.Files = append(out.Files, fileRecord{
+			File:            filepath.ToSlash(f.Name),
+			Functions:       f.Totals.Funcs,
+			BranchFactor:    f.BranchFactor,
+			HalsteadVolume:  f.HalsteadVolume,
+			Lines:           lines{Physical: f.Lines.Physical, Comment: f.Lines.Comment},
+			Maintainability: f.Maintainability,
+		})
+	}
+	for _, fn := range r.Funcs {
+		out.Functions = append(out.Functions, newRecord(fn))
+	}
+	for _, v := range r.Violations {
+		out.Violations = append(out.Violations, violationRecord{
+			Package:  v.Func.ID.Pkg,
+			Function: v.Func.ID.Local(),
+			File:     filepath.ToSlash(v.Func.Pos.Filename),
+			Line:     v.Func.Pos.Line,
+			Metric:   v.Metric.String(),
+			Value:    v.Value,
+			Limit:    v.Limit,
+		})
+	}
+	for _, w := range r.Warnings {
+		out.Warnings = append(out.Warnings, warningRecord{
+			Package:   w.Func.ID.Pkg,
+			Function:  w.Func.ID.Local(),
+			File:      filepath.ToSlash(w.Directive.Pos.Filename),
+			Line:      w.Directive.Pos.Line,
+			Directive: w.Directive.Text,
+			Message:   w.Msg,
+		})
+	}
+	for _, c := range r.Changes {
+		out.Changes = append(out.Changes, newChangeRecord(c))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteCSV writes one CSV row per function, after a header row naming the
+// columns. Violations are not part of the CSV format.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader())
+	for _, fn := range r.Funcs {
+		cw.Write(newRecord(fn).csv(r.policy(), r.Maintainability))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// The subset of SARIF 2.1.0 (https://docs.oasis-open.org/sarif/sarif/v2.1.0/)
+// written by WriteSARIF.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool       `json:"tool"`
+		Results    []sarifResult   `json:"results"`
+		Properties sarifProperties `json:"properties"`
+	}
+	sarifProperties struct {
+		Policy *Policy `json:"policy"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+// sarifRuleID returns the id of the SARIF rule for limits on metric m.
+func sarifRuleID(m Metric) string {
+	return "max-" + m.String()
+}
+
+// sarifDirectiveRule is the id of the SARIF rule for problems with directives.
+const sarifDirectiveRule = "directive"
+
+// WriteSARIF writes the violations of the report as a SARIF 2.1.0 log for
+// code scanning tools, one result per violation at the function declaration,
+// followed by one result of level warning per warning at the directive.
+// The policy is recorded in the properties of the run.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{Name: "branchfactor", Rules: []sarifRule{}}
+	for m := range metricNames {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               sarifRuleID(Metric(m)),
+			ShortDescription: sarifMessage{fmt.Sprintf("Function exceeds its %s limit", Metric(m).Description())},
+		})
+	}
+	driver.Rules = append(driver.Rules, sarifRule{
+		ID:               sarifDirectiveRule,
+		ShortDescription: sarifMessage{"Directive is malformed, has no reason or is no longer needed"},
+	})
+
+	run := sarifRun{
+		Tool:       sarifTool{driver},
+		Results:    []sarifResult{},
+		Properties: sarifProperties{r.policy()},
+	}
+	for _, v := range r.Violations {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifRuleID(v.Metric),
+			Level:   "error",
+			Message: sarifMessage{fmt.Sprintf("%s has a %s of %d, which exceeds the limit of %d", v.Func.ID, v.Metric.Description(), v.Value, v.Limit)},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{sarifURI(v.Func.Pos.Filename)},
+				Region:           sarifRegion{StartLine: v.Func.Pos.Line, StartColumn: v.Func.Pos.Column},
+			}}},
+		})
+	}
+	for _, warning := range r.Warnings {
+		pos := warning.Directive.Pos
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifDirectiveRule,
+			Level:   "warning",
+			Message: sarifMessage{fmt.Sprintf("%s: //%s: %s", warning.Func.ID, warning.Directive.Text, warning.Msg)},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{sarifURI(pos.Filename)},
+				Region:           sarifRegion{StartLine: pos.Line, StartColumn: pos.Column},
+			}}},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI returns the URI of the file filename: a relative reference for
+// relative paths, so code scanning resolves it against the repository root,
+// and a file URI otherwise.
+func sarifURI(filename string) string {
+	uri := filepath.ToSlash(filename)
+	if filepath.IsAbs(filename) {
+		if !strings.HasPrefix(uri, "/") {
+			uri = "/" + uri // Windows drive letter
+		}
+		return "file://" + uri
+	}
+	return strings.TrimPrefix(uri, "./")
+}
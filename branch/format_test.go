@@ -0,0 +1,161 @@
+package branch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"go/token"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var bigNPath, _ = new(big.Int).SetString("123456789012345678901234567890", 10)
+
+func testReport() *Report {
+	small := &Func{
+		ID:           FuncID{Pkg: "example.com/m", Name: "Small"},
+		Pos:          token.Position{Filename: "m/a.go", Line: 3, Column: 1},
+		BranchFactor: 1, Cyclomatic: 2, Cognitive: 1,
+		NPath: big.NewInt(2),
+	}
+	big := &Func{
+		ID:           FuncID{Pkg: "example.com/m", Recv: "*T", Name: "Big"},
+		Pos:          token.Position{Filename: "m/a.go", Line: 10, Column: 1},
+		BranchFactor: 12, Cyclomatic: 9, Cognitive: 20,
+		NPath:           bigNPath,
+		Halstead:        Halstead{Volume: 250.5, Difficulty: 12, Effort: 3006},
+		Lines:           Lines{Physical: 20, Logical: 15, Comment: 2},
+		Maintainability: 42.3,
+		Breakdown:       Breakdown{Total: 12, Kinds: map[Kind]uint{KindIf: 10, KindBreak: 2}},
+		Nesting:         Nesting{Max: 3, Deepest: token.Position{Filename: "m/a.go", Line: 14}, Profile: []uint{4, 3, 2, 1}},
+	}
+	return &Report{
+		Funcs:      []*Func{small, big},
+		Violations: []Violation{{Func: big, Metric: MetricBranchFactor, Value: 12, Limit: 10}},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON returned error %v\n", err)
+	}
+
+	var out struct {
+		Policy     *Policy
+		Functions  []map[string]interface{}
+		Violations []map[string]interface{}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("WriteJSON wrote invalid JSON: %v\n%s", err, &buf)
+	}
+	if out.Policy == nil || out.Policy.String() != DefaultPolicy.String() {
+		t.Errorf("WriteJSON wrote policy %v, want %v\n", out.Policy, DefaultPolicy)
+	}
+	if len(out.Functions) != 2 || len(out.Violations) != 1 {
+		t.Fatalf("WriteJSON wrote %d functions and %d violations, want 2 and 1\n",
+			len(out.Functions), len(out.Violations))
+	}
+
+	fn := out.Functions[1]
+	want := map[string]interface{}{
+		"package": "example.com/m", "function": "(*T).Big", "file": "m/a.go",
+		"line": 10.0, "column": 1.0, "branch_factor": 12.0, "cyclomatic": 9.0, "cognitive": 20.0,
+		"max_depth": 3.0, "deepest_line": 14.0, "maintainability": 42.3,
+	}
+	if !strings.Contains(buf.String(), `"formula": "max(0, (171 - 5.2*ln(V) - 0.23*G - 16.2*ln(L)) * 100/171)"`) {
+		t.Errorf("WriteJSON did not document the maintainability index\n%s", &buf)
+	}
+	if !strings.Contains(buf.String(), `"npath": 123456789012345678901234567890`) {
+		t.Errorf("WriteJSON did not write the exact NPath\n%s", &buf)
+	}
+	for key, value := range want {
+		if fn[key] != value {
+			t.Errorf("WriteJSON wrote %s = %v, want %v\n", key, fn[key], value)
+		}
+	}
+	if kinds, ok := fn["kinds"].(map[string]interface{}); !ok || kinds["if"] != 10.0 || kinds["break"] != 2.0 {
+		t.Errorf("WriteJSON wrote kinds %v, want if: 10, break: 2\n", fn["kinds"])
+	}
+	if v := out.Violations[0]; v["metric"] != "branch" || v["value"] != 12.0 || v["limit"] != 10.0 {
+		t.Errorf("WriteJSON wrote violation %v\n", v)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV returned error %v\n", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteCSV wrote invalid CSV: %v\n", err)
+	}
+	want := [][]string{
+		{"package", "function", "file", "line", "column", "branch_factor",
+			"if", "for", "range", "switch", "typeswitch", "goto", "break", "continue", "fallthrough",
+			"elseif", "select", "defer", "commclause",
+			"cyclomatic", "cognitive", "concurrency", "npath",
+			"halstead_volume", "halstead_difficulty", "halstead_effort",
+			"physical_lines", "logical_lines", "comment_lines", "maintainability", "max_depth", "policy", "mi_variant"},
+		{"example.com/m", "Small", "m/a.go", "3", "1", "1",
+			"0", "0", "0", "0", "0", "0", "0", "0", "0",
+			"0", "0", "0", "0",
+			"2", "1", "0", "2", "0.00", "0.00", "0.00", "0", "0", "0", "0.00", "0", "default", "microsoft"},
+		{"example.com/m", "(*T).Big", "m/a.go", "10", "1", "12",
+			"10", "0", "0", "0", "0", "0", "2", "0", "0",
+			"0", "0", "0", "0",
+			"9", "20", "0", "123456789012345678901234567890", "250.50", "12.00", "3006.00", "20", "15", "2", "42.30", "3", "default", "microsoft"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("WriteCSV wrote %d rows, want %d\n", len(rows), len(want))
+	}
+	for i := range want {
+		if !equalStrings(rows[i], want[i]) {
+			t.Errorf("WriteCSV row %d = %v, want %v\n", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteSARIF(&buf); err != nil {
+		t.Fatalf("WriteSARIF returned error %v\n", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIF wrote invalid JSON: %v\n", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("WriteSARIF wrote version %q with %d runs, want 2.1.0 with 1\n", log.Version, len(log.Runs))
+	}
+	if p := log.Runs[0].Properties.Policy; p == nil || p.Name != "default" {
+		t.Errorf("WriteSARIF wrote policy %v, want default\n", p)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("WriteSARIF wrote %d results, want 1\n", len(results))
+	}
+	loc := results[0].Locations[0].PhysicalLocation
+	if results[0].RuleID != "max-branch" || loc.ArtifactLocation.URI != "m/a.go" || loc.Region.StartLine != 10 {
+		t.Errorf("WriteSARIF wrote result %+v\n", results[0])
+	}
+}
+
+func TestSarifURI(t *testing.T) {
+	tests := []struct {
+		filename, uri string
+	}{
+		{"a.go", "a.go"},
+		{"./pkg/a.go", "pkg/a.go"},
+		{"/src/pkg/a.go", "file:///src/pkg/a.go"},
+	}
+	for _, test := range tests {
+		if uri := sarifURI(test.filename); uri != test.uri {
+			t.Errorf("sarifURI(%q) = %q, want %q\n", test.filename, uri, test.uri)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// FuncID identifies a function declaration. Unlike the bare function name it
+// tells methods of different types apart, so (*A).String and (*B).String do
+// not overwrite each other.
+type FuncID struct {
+	// Pkg is the package the function is declared in: its path when a
+	// directory is analyzed (see Package.Path), and its name otherwise.
+	Pkg string
+
+	// Recv is the receiver type of a method as written in the source, with
+	// pointer-ness and type parameters, e.g. "*List[T]". It is empty for plain
+	// functions.
+	Recv string
+
+	// Name is the name of the function.
+	Name string
+
+	// Pos is only set for functions that can be declared more than once in
+	// the same package (init and _). For every other function the identity
+	// does not depend on where the declaration is, so it is stable when code
+	// moves around.
+	Pos token.Position
+}
+
+// newFuncID returns the identity of fn declared in package pkg.
+func newFuncID(fset *token.FileSet, pkg string, fn *ast.FuncDecl) FuncID {
+	id := FuncID{Pkg: pkg, Name: fn.Name.Name}
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		id.Recv = recvString(fn.Recv.List[0].Type)
+	}
+	if id.Recv == "" && (id.Name == "init" || id.Name == "_") {
+		id.Pos = fset.Position(fn.Pos())
+		// only the file name and line are needed to tell them apart
+		id.Pos.Filename = filepath.ToSlash(id.Pos.Filename)
+		id.Pos.Offset = 0
+		id.Pos.Column = 0
+	}
+	return id
+}
+
+// recvString formats a receiver type, e.g. "*T", "T" or "*List[K, V]".
+func recvString(x ast.Expr) string {
+	switch t := x.(type) {
+	case *ast.ParenExpr: // func (p (*T)) m()
+		return recvString(t.X)
+	case *ast.StarExpr:
+		return "*" + recvString(t.X)
+	}
+	return types.ExprString(x)
+}
+
+// Local returns the identity without the package name. For a plain function
+// this is just its name, so it matches the keys ComputeBranchFactors has
+// always used.
+func (id FuncID) Local() string {
+	name := id.Name
+	if id.Recv != "" {
+		if id.Recv[0] == '*' {
+			name = fmt.Sprintf("(%s).%s", id.Recv, id.Name)
+		} else {
+			name = fmt.Sprintf("%s.%s", id.Recv, id.Name)
+		}
+	}
+	if id.Pos.IsValid() {
+		name = fmt.Sprintf("%s@%s:%d", name, id.Pos.Filename, id.Pos.Line)
+	}
+	return name
+}
+
+// String returns the qualified identity, e.g. "main.(*List[T]).Push" or
+// "main.init@src.go:12", and implements the Stringer interface for FuncID.
+func (id FuncID) String() string {
+	if id.Pkg == "" {
+		return id.Local()
+	}
+	return id.Pkg + "." + id.Local()
+}
@@ -0,0 +1,88 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestFuncID(t *testing.T) {
+	var test_code = `package shapes
+
+type A struct{}
+type B struct{}
+type List[T any] struct{}
+type Map[K comparable, V any] struct{}
+
+func (a *A) String() string {
+	if a == nil {
+		return "nil"
+	}
+	return "A"
+}
+
+func (B) String() string {
+	return "B"
+}
+
+func (l *List[T]) Push(v T) {
+	for {
+		break
+	}
+}
+
+func (m Map[K, V]) Len() int {
+	return 0
+}
+
+func init() {
+	if true {
+	}
+}
+
+func init() {
+	for {
+	}
+}
+
+func String() string {
+	return ""
+}
+`
+
+	tests := []struct {
+		id       string
+		local    string
+		branches uint
+	}{
+		{"shapes.(*A).String", "(*A).String", 1},
+		{"shapes.B.String", "B.String", 0},
+		{"shapes.(*List[T]).Push", "(*List[T]).Push", 2},
+		{"shapes.Map[K, V].Len", "Map[K, V].Len", 0},
+		{"shapes.init@ids.go:29", "init@ids.go:29", 1},
+		{"shapes.init@ids.go:34", "init@ids.go:34", 1},
+		{"shapes.String", "String", 0},
+	}
+
+	funcs, err := AnalyzeSource("ids.go", test_code)
+	if err != nil {
+		t.Fatalf("AnalyzeSource returned error %v\n", err)
+	}
+	if len(funcs) != len(tests) {
+		t.Fatalf("AnalyzeSource returned %d functions, want %d\n", len(funcs), len(tests))
+	}
+	for i, test := range tests {
+		fn := funcs[i]
+		if fn.ID.String() != test.id || fn.ID.Local() != test.local {
+			t.Errorf("function %d has id %q (local %q), want %q (local %q)\n",
+				i, fn.ID, fn.ID.Local(), test.id, test.local)
+		}
+		if fn.BranchFactor != test.branches {
+			t.Errorf("AnalyzeSource(%v) = %d, want %d\n", test.id, fn.BranchFactor, test.branches)
+		}
+	}
+
+	branch_factors, _ := BranchFactors("ids.go", test_code)
+	if len(branch_factors) != len(tests) {
+		t.Errorf("BranchFactors returned %d functions, want %d: %v\n",
+			len(branch_factors), len(tests), branch_factors)
+	}
+}
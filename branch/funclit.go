@@ -0,0 +1,100 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// walker holds the settings shared by the metrics of a function.
+type walker struct {
+	// skipLits does not descend into the function literals of a function,
+	// so their branches are not charged to it.
+	skipLits bool
+
+	// policy decides which statements count as branches.
+	policy *Policy
+
+	// info holds the types and constant values of the expressions of the
+	// package; it may be nil.
+	info *types.Info
+
+	// comments are the comments of the file being analyzed.
+	comments []*ast.CommentGroup
+
+	// miVariant is the formula of the maintainability index.
+	miVariant MIVariant
+
+	// excludeConst leaves if, for and switch statements with constant
+	// conditions out of the branch factor, see isConstant.
+	excludeConst bool
+
+	// elseIfs holds the if statements that are the else branch of another
+	// if statement. It is filled in by inspect.
+	elseIfs map[*ast.IfStmt]bool
+}
+
+// walker returns a walker with the settings of the configuration, for a
+// package with the type information info.
+func (c *Config) walker(info *types.Info) *walker {
+	return &walker{
+		skipLits:     c.ExcludeFuncLits,
+		policy:       c.policy(),
+		info:         info,
+		excludeConst: c.ExcludeConstant,
+		miVariant:    c.Maintainability,
+	}
+}
+
+// inspect is like ast.Inspect(fn, f), but if w.skipLits is set it does not
+// visit the function literals nested in fn.
+func (w *walker) inspect(fn ast.Node, f func(ast.Node) bool) {
+	ast.Inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			if w.skipLits && node != fn {
+				return false
+			}
+		case *ast.IfStmt:
+			if els, ok := n.Else.(*ast.IfStmt); ok {
+				if w.elseIfs == nil {
+					w.elseIfs = make(map[*ast.IfStmt]bool)
+				}
+				w.elseIfs[els] = true
+			}
+		}
+		return f(node)
+	})
+}
+
+// isElseIf reports whether node is the if statement of an else if. It only
+// knows about the statements visited by inspect so far.
+func (w *walker) isElseIf(node ast.Node) bool {
+	n, ok := node.(*ast.IfStmt)
+	return ok && w.elseIfs[n]
+}
+
+// funcLits returns the results of the function literals in body, which is part
+// of the function id. The literals are numbered in source order and named by
+// prefix and their number; literals nested in them are handled recursively.
+func (w *walker) funcLits(fset *token.FileSet, id FuncID, prefix string, body ast.Node) []*Func {
+	var funcs []*Func
+	n := 0
+	ast.Inspect(body, func(node ast.Node) bool {
+		lit, ok := node.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		n++
+		litID := id
+		litID.Name = fmt.Sprintf("%s%d", prefix, n)
+		result := w.analyze(fset, litID, lit)
+		result.Lit = true
+		funcs = append(funcs, result)
+		funcs = append(funcs, w.funcLits(fset, litID, litID.Name+".", lit.Body)...)
+		// nested literals were handled above
+		return false
+	})
+	return funcs
+}
@@ -0,0 +1,118 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestFuncLits(t *testing.T) {
+	var test_code = `package main
+
+func Outer(xs []int) {
+	for range xs {
+	}
+	go func() {
+		if len(xs) > 0 {
+			return
+		}
+		f := func() {
+			for {
+				break
+			}
+		}
+		f()
+	}()
+	defer func() {
+		switch {
+		}
+	}()
+}
+
+func (s *S) M() {
+	do(func() {
+		if s == nil {
+		}
+	})
+}
+
+type S struct{}
+
+func do(f func()) {}
+`
+
+	tests := []struct {
+		config Config
+		funcs  []string
+		counts []uint
+	}{
+		{Config{},
+			[]string{"Outer", "(*S).M", "do"},
+			[]uint{5, 1, 0}},
+		{Config{FuncLits: true},
+			[]string{"Outer", "Outer.func1", "Outer.func1.1", "Outer.func2", "(*S).M", "(*S).M.func1", "do"},
+			[]uint{5, 3, 2, 1, 1, 1, 0}},
+		{Config{FuncLits: true, ExcludeFuncLits: true},
+			[]string{"Outer", "Outer.func1", "Outer.func1.1", "Outer.func2", "(*S).M", "(*S).M.func1", "do"},
+			[]uint{1, 1, 2, 1, 0, 1, 0}},
+	}
+
+	for _, test := range tests {
+		funcs, err := test.config.AnalyzeSource("src.go", test_code)
+		if err != nil {
+			t.Fatalf("AnalyzeSource returned error %v\n", err)
+		}
+		if len(funcs) != len(test.funcs) {
+			t.Errorf("AnalyzeSource(%+v) returned %d functions, want %d\n", test.config, len(funcs), len(test.funcs))
+			continue
+		}
+		for i, fn := range funcs {
+			if fn.ID.Local() != test.funcs[i] || fn.BranchFactor != test.counts[i] {
+				t.Errorf("AnalyzeSource(%+v) function %d = %v with %d, want %v with %d\n",
+					test.config, i, fn.ID.Local(), fn.BranchFactor, test.funcs[i], test.counts[i])
+			}
+		}
+	}
+
+	funcs, _ := (&Config{FuncLits: true}).AnalyzeSource("src.go", test_code)
+	if pos := funcs[1].Pos; pos.Line != 6 || pos.Column != 5 {
+		t.Errorf("Outer.func1 is at %v, want src.go:6:5\n", pos)
+	}
+	if funcs[1].Cognitive != 3 {
+		t.Errorf("cognitive(Outer.func1) = %d, want 3\n", funcs[1].Cognitive)
+	}
+}
+
+func TestFuncLits_Totals(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": `package a
+
+func Outer(c chan int) {
+	go func() {
+		if len(c) > 0 {
+		}
+	}()
+}
+`})
+
+	tests := []struct {
+		cfg          Config
+		funcs        int
+		branchFactor uint
+	}{
+		{Config{}, 1, 1},
+		{Config{FuncLits: true}, 1, 1},
+		{Config{FuncLits: true, ExcludeFuncLits: true}, 2, 1},
+	}
+	for _, test := range tests {
+		pkgs, err := test.cfg.AnalyzeDir(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := pkgs[0].Files[0]
+		if file.Totals.Funcs != test.funcs || file.BranchFactor != test.branchFactor {
+			t.Errorf("AnalyzeDir(%+v) totals = %d functions, branch factor %d, want %d, %d\n",
+				test.cfg, file.Totals.Funcs, file.BranchFactor, test.funcs, test.branchFactor)
+		}
+		if pkgs[0].Totals != file.Totals {
+			t.Errorf("AnalyzeDir(%+v) package totals = %+v, want %+v\n", test.cfg, pkgs[0].Totals, file.Totals)
+		}
+	}
+}
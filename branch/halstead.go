@@ -0,0 +1,165 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+)
+
+// Halstead holds the Halstead software science metrics of a function, which
+// measure its size by the operators and operands it uses.
+//
+// Operands are identifiers and literals. Operators are the operator tokens
+// (+, &&, :=, <- ...), the keywords of statements and declarations (if, for,
+// return, func ...), and the punctuation of calls, index and slice
+// expressions, selectors, type assertions and composite literals, each
+// counted once per use.
+type Halstead struct {
+	// Operators and Operands are the numbers of distinct operators and
+	// operands (n1 and n2).
+	Operators int
+	Operands  int
+
+	// TotalOperators and TotalOperands are the numbers of uses of operators
+	// and operands (N1 and N2).
+	TotalOperators int
+	TotalOperands  int
+
+	// Volume is N * log2(n), where N = N1 + N2 is the program length and
+	// n = n1 + n2 the vocabulary.
+	Volume float64
+
+	// Difficulty is n1/2 * N2/n2.
+	Difficulty float64
+
+	// Effort is Difficulty * Volume.
+	Effort float64
+}
+
+// halstead computes the Halstead metrics of fn.
+func (w *walker) halstead(fn ast.Node) Halstead {
+	operators := make(map[string]int)
+	operands := make(map[string]int)
+	op := func(name string) {
+		operators[name]++
+	}
+
+	w.inspect(fn, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Ident:
+			operands[n.Name]++
+		case *ast.BasicLit:
+			operands[n.Value]++
+
+		case *ast.BinaryExpr:
+			op(n.Op.String())
+		case *ast.UnaryExpr:
+			op(n.Op.String())
+		case *ast.StarExpr:
+			op("*")
+		case *ast.AssignStmt:
+			op(n.Tok.String())
+		case *ast.IncDecStmt:
+			op(n.Tok.String())
+		case *ast.SendStmt:
+			op("<-")
+		case *ast.KeyValueExpr:
+			op(":")
+		case *ast.CallExpr:
+			op("()")
+		case *ast.ParenExpr:
+			op("()")
+		case *ast.IndexExpr:
+			op("[]")
+		case *ast.SliceExpr:
+			op("[:]")
+		case *ast.SelectorExpr:
+			op(".")
+		case *ast.TypeAssertExpr:
+			op(".()")
+		case *ast.CompositeLit:
+			op("{}")
+		case *ast.ArrayType:
+			op("[]")
+		case *ast.MapType:
+			op("map")
+		case *ast.ChanType:
+			op("chan")
+		case *ast.InterfaceType:
+			op("interface")
+		case *ast.StructType:
+			op("struct")
+		case *ast.Ellipsis:
+			op("...")
+
+		case *ast.FuncDecl:
+			op("func")
+		case *ast.FuncLit:
+			op("func")
+		case *ast.GenDecl:
+			op(n.Tok.String())
+		case *ast.ValueSpec:
+			if len(n.Values) > 0 {
+				op("=")
+			}
+		case *ast.LabeledStmt:
+			op(":")
+		case *ast.IfStmt:
+			op("if")
+			if n.Else != nil {
+				op("else")
+			}
+		case *ast.ForStmt:
+			op("for")
+		case *ast.RangeStmt:
+			op("for")
+			op("range")
+			if n.Tok != token.ILLEGAL {
+				op(n.Tok.String())
+			}
+		case *ast.SwitchStmt:
+			op("switch")
+		case *ast.TypeSwitchStmt:
+			op("switch")
+		case *ast.SelectStmt:
+			op("select")
+		case *ast.CaseClause:
+			if n.List == nil {
+				op("default")
+			} else {
+				op("case")
+			}
+		case *ast.CommClause:
+			if n.Comm == nil {
+				op("default")
+			} else {
+				op("case")
+			}
+		case *ast.ReturnStmt:
+			op("return")
+		case *ast.BranchStmt:
+			op(n.Tok.String())
+		case *ast.GoStmt:
+			op("go")
+		case *ast.DeferStmt:
+			op("defer")
+		}
+		return true
+	})
+
+	h := Halstead{Operators: len(operators), Operands: len(operands)}
+	for _, count := range operators {
+		h.TotalOperators += count
+	}
+	for _, count := range operands {
+		h.TotalOperands += count
+	}
+	if n := h.Operators + h.Operands; n > 0 {
+		h.Volume = float64(h.TotalOperators+h.TotalOperands) * math.Log2(float64(n))
+	}
+	if h.Operands > 0 {
+		h.Difficulty = float64(h.Operators) / 2 * float64(h.TotalOperands) / float64(h.Operands)
+	}
+	h.Effort = h.Difficulty * h.Volume
+	return h
+}
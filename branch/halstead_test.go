@@ -0,0 +1,52 @@
+package branch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHalstead(t *testing.T) {
+	var test_code = `package main
+
+func add(a, b int) int {
+	return a + b
+}
+
+func loop(xs []int) {
+	for i := range xs {
+		xs[i]++
+	}
+}
+
+func empty() {
+}
+`
+	want := map[string]Halstead{
+		// operators: func return +, operands: add a a b b int int
+		"add": {Operators: 3, Operands: 4, TotalOperators: 3, TotalOperands: 7,
+			Volume: 10 * math.Log2(7), Difficulty: 3.0 / 2 * 7 / 4},
+		// operators: func [] for range := [] ++, operands: loop xs xs xs int i i
+		"loop": {Operators: 6, Operands: 4, TotalOperators: 7, TotalOperands: 7,
+			Volume: 14 * math.Log2(10), Difficulty: 6.0 / 2 * 7 / 4},
+		// operators: func, operands: empty
+		"empty": {Operators: 1, Operands: 1, TotalOperators: 1, TotalOperands: 1,
+			Volume: 2, Difficulty: 0.5},
+	}
+	funcs := analyzeByName(t, test_code)
+	for name, h := range want {
+		h.Effort = h.Volume * h.Difficulty
+		got := funcs[name].Halstead
+		if got.Operators != h.Operators || got.Operands != h.Operands ||
+			got.TotalOperators != h.TotalOperators || got.TotalOperands != h.TotalOperands {
+			t.Errorf("halstead(%v) = %+v, want %+v\n", name, got, h)
+			continue
+		}
+		if !closeTo(got.Volume, h.Volume) || !closeTo(got.Difficulty, h.Difficulty) || !closeTo(got.Effort, h.Effort) {
+			t.Errorf("halstead(%v) = %+v, want %+v\n", name, got, h)
+		}
+	}
+}
+
+func closeTo(x, y float64) bool {
+	return math.Abs(x-y) < 1e-9
+}
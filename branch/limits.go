@@ -0,0 +1,187 @@
+package branch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Metric selects one of the per-function figures of Func.
+type Metric int
+
+// Enumerates the metrics limits can be set on.
+const (
+	MetricBranchFactor Metric = iota
+	MetricCyclomatic
+	MetricCognitive
+	MetricConcurrency
+	MetricNesting
+	MetricNPath
+)
+
+var metricNames = []string{
+	MetricBranchFactor: "branch",
+	MetricCyclomatic:   "cyclomatic",
+	MetricCognitive:    "cognitive",
+	MetricConcurrency:  "concurrency",
+	MetricNesting:      "nesting",
+	MetricNPath:        "npath",
+}
+
+var metricDescriptions = []string{
+	MetricBranchFactor: "branch factor",
+	MetricCyclomatic:   "cyclomatic complexity",
+	MetricCognitive:    "cognitive complexity",
+	MetricConcurrency:  "concurrency complexity",
+	MetricNesting:      "nesting depth",
+	MetricNPath:        "NPath complexity",
+}
+
+// Description returns the name of the metric for messages, e.g. "branch
+// factor".
+func (m Metric) Description() string {
+	if m < 0 || int(m) >= len(metricDescriptions) {
+		return m.String()
+	}
+	return metricDescriptions[m]
+}
+
+// String returns the name of the metric as accepted by ParseMetric.
+func (m Metric) String() string {
+	if m < 0 || int(m) >= len(metricNames) {
+		return fmt.Sprintf("Metric(%d)", int(m))
+	}
+	return metricNames[m]
+}
+
+// ParseMetric returns the metric called name, e.g. "branch".
+func ParseMetric(name string) (Metric, error) {
+	for m, s := range metricNames {
+		if s == name {
+			return Metric(m), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown metric %q (want one of %s)", name, strings.Join(metricNames, ", "))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Metric) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *Metric) UnmarshalText(text []byte) error {
+	metric, err := ParseMetric(string(text))
+	if err != nil {
+		return err
+	}
+	*m = metric
+	return nil
+}
+
+// Value returns the value of the metric for fn. An NPath that does not fit
+// into a uint is returned as the largest uint.
+func (m Metric) Value(fn *Func) uint {
+	switch m {
+	case MetricNPath:
+		if fn.NPath == nil {
+			return 0
+		}
+		if !fn.NPath.IsUint64() || uint64(uint(fn.NPath.Uint64())) != fn.NPath.Uint64() {
+			return ^uint(0)
+		}
+		return uint(fn.NPath.Uint64())
+	case MetricCyclomatic:
+		return fn.Cyclomatic
+	case MetricCognitive:
+		return fn.Cognitive
+	case MetricConcurrency:
+		return fn.Concurrency
+	case MetricNesting:
+		return uint(fn.Nesting.Max)
+	}
+	return fn.BranchFactor
+}
+
+// Limits are the largest values of a metric a function may have.
+type Limits struct {
+	// Metric is the metric that is limited.
+	Metric Metric
+
+	// Max is the limit for every function. Zero means no limit.
+	Max uint
+
+	// Packages overrides Max for the functions of some packages. The key is
+	// a package path (see FuncID.Pkg), or a path followed by "/..." for the
+	// package and everything below it. The most specific matching key wins.
+	Packages map[string]uint
+}
+
+// Violation is a function whose metric exceeds its limit.
+type Violation struct {
+	Func   *Func
+	Metric Metric
+	Value  uint
+	Limit  uint
+}
+
+// String formats the violation for humans and implements the Stringer
+// interface for Violation.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s %d exceeds limit %d", v.Func.Pos, v.Func.ID, v.Metric.Description(), v.Value, v.Limit)
+}
+
+// Limit returns the limit for the function fn and whether there is one. A
+// //branch:ignore directive of fn removes the limit and a //branch:max=N
+// directive replaces the limit of the branch factor, see Directive.
+func (l *Limits) Limit(fn *Func) (uint, bool) {
+	if d, ok := fn.directive(); ok {
+		switch {
+		case d.Ignore:
+			return 0, false
+		case l.Metric == MetricBranchFactor:
+			return d.Max, true
+		}
+	}
+	return l.limit(fn)
+}
+
+// limit returns the limit for fn as configured, ignoring its directives.
+func (l *Limits) limit(fn *Func) (uint, bool) {
+	limit, best := l.Max, 0
+	for key, max := range l.Packages {
+		if score := matchPackage(key, fn.ID.Pkg); score > best {
+			limit, best = max, score
+		}
+	}
+	return limit, limit > 0
+}
+
+// matchPackage reports how specifically the package path matches pattern,
+// which is a package path optionally followed by "/...". The result is 0 if
+// it does not match; longer patterns score higher, and for patterns of the same
+// length an exact path scores higher than "/...".
+func matchPackage(pattern, path string) int {
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := strings.TrimSuffix(pattern, "/...")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return 2*len(prefix) + 1
+		}
+		return 0
+	}
+	if path == pattern {
+		return 2*len(pattern) + 2
+	}
+	return 0
+}
+
+// Check returns the functions that exceed their limit, in the order given.
+func (l *Limits) Check(funcs []*Func) []Violation {
+	var violations []Violation
+	for _, fn := range funcs {
+		limit, ok := l.Limit(fn)
+		if value := l.Metric.Value(fn); ok && value > limit {
+			violations = append(violations, Violation{Func: fn, Metric: l.Metric, Value: value, Limit: limit})
+		}
+	}
+	return violations
+}
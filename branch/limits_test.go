@@ -0,0 +1,53 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	funcs := []*Func{
+		{ID: FuncID{Pkg: "m", Name: "Small"}, BranchFactor: 2, Cyclomatic: 3},
+		{ID: FuncID{Pkg: "m", Name: "Big"}, BranchFactor: 10, Cyclomatic: 12},
+		{ID: FuncID{Pkg: "m/legacy", Name: "Huge"}, BranchFactor: 30, Cyclomatic: 31},
+		{ID: FuncID{Pkg: "m/legacy/sub", Name: "Mid"}, BranchFactor: 15, Cyclomatic: 16},
+		{ID: FuncID{Pkg: "m/gen", Name: "Gen"}, BranchFactor: 50, Cyclomatic: 50},
+	}
+
+	tests := []struct {
+		limits Limits
+		want   []string
+	}{
+		{Limits{}, nil},
+		{Limits{Max: 5}, []string{"m.Big", "m/legacy.Huge", "m/legacy/sub.Mid", "m/gen.Gen"}},
+		{Limits{Max: 5, Packages: map[string]uint{"m/legacy/...": 40, "m/gen": 0}},
+			[]string{"m.Big"}},
+		{Limits{Max: 5, Packages: map[string]uint{"m/legacy/...": 40, "m/legacy/sub": 10}},
+			[]string{"m.Big", "m/legacy/sub.Mid", "m/gen.Gen"}},
+		{Limits{Metric: MetricCyclomatic, Max: 11, Packages: map[string]uint{"m/legacy": 31}},
+			[]string{"m.Big", "m/legacy/sub.Mid", "m/gen.Gen"}},
+	}
+
+	for i, test := range tests {
+		var got []string
+		for _, v := range test.limits.Check(funcs) {
+			got = append(got, v.Func.ID.String())
+			if v.Value != v.Metric.Value(v.Func) || v.Value <= v.Limit {
+				t.Errorf("test %d: bad violation %v\n", i, v)
+			}
+		}
+		if !equalStrings(got, test.want) {
+			t.Errorf("test %d: Check(%+v) = %v, want %v\n", i, test.limits, got, test.want)
+		}
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	for _, m := range []Metric{MetricBranchFactor, MetricCyclomatic, MetricCognitive} {
+		if got, err := ParseMetric(m.String()); err != nil || got != m {
+			t.Errorf("ParseMetric(%q) = %v, %v, want %v\n", m.String(), got, err, m)
+		}
+	}
+	if _, err := ParseMetric("lines"); err == nil {
+		t.Errorf("ParseMetric(\"lines\") did not return an error\n")
+	}
+}
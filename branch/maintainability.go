@@ -0,0 +1,201 @@
+package branch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"math"
+	"strings"
+)
+
+// Lines counts the lines of a function or file.
+type Lines struct {
+	// Physical is the number of lines, from the doc comment of a function
+	// (or its func keyword if it has none) to its closing brace.
+	Physical int
+
+	// Logical is the number of statements, not counting blocks, case clauses
+	// and empty statements.
+	Logical int
+
+	// Comment is the number of lines holding a comment.
+	Comment int
+}
+
+// CommentRatio returns the fraction of the physical lines holding a comment,
+// between 0 and 1.
+func (l Lines) CommentRatio() float64 {
+	if l.Physical == 0 {
+		return 0
+	}
+	return math.Min(1, float64(l.Comment)/float64(l.Physical))
+}
+
+// MIVariant selects the formula of the maintainability index. The zero value
+// is MIMicrosoft.
+//
+// All variants start from the one by Oman and Hagemeister,
+//
+//	MI = 171 - 5.2 ln(V) - 0.23 G - 16.2 ln(L)
+//
+// where V is the Halstead volume, G the branch factor (in place of the
+// cyclomatic complexity of the original) and L the number of physical lines.
+type MIVariant int
+
+const (
+	// MIMicrosoft is the variant of Visual Studio, which scales the index
+	// to 0..100 and does not take comments into account:
+	// max(0, MI * 100 / 171). Values below 10 are considered hard to
+	// maintain, 10 to 20 moderately maintainable.
+	MIMicrosoft MIVariant = iota
+
+	// MIOriginal is the unscaled index MI, which can be negative. Values
+	// below 65 are considered hard to maintain, 65 to 85 moderately
+	// maintainable.
+	MIOriginal
+
+	// MISEI is the variant of the Software Engineering Institute, which
+	// rewards comments: MI + 50 sin(sqrt(2.4 C)), where C is the comment
+	// ratio between 0 and 1.
+	MISEI
+
+	// MILogical is MIMicrosoft with the number of logical lines S in place
+	// of L, so that blank lines, comments and the way statements are
+	// wrapped do not change the index.
+	MILogical
+)
+
+var miVariantNames = [...]string{
+	MIMicrosoft: "microsoft",
+	MIOriginal:  "original",
+	MISEI:       "sei",
+	MILogical:   "logical",
+}
+
+var miVariantFormulas = [...]string{
+	MIMicrosoft: "max(0, (171 - 5.2*ln(V) - 0.23*G - 16.2*ln(L)) * 100/171)",
+	MIOriginal:  "171 - 5.2*ln(V) - 0.23*G - 16.2*ln(L)",
+	MISEI:       "171 - 5.2*ln(V) - 0.23*G - 16.2*ln(L) + 50*sin(sqrt(2.4*C))",
+	MILogical:   "max(0, (171 - 5.2*ln(V) - 0.23*G - 16.2*ln(S)) * 100/171)",
+}
+
+// String returns the name of the variant, e.g. "microsoft".
+func (v MIVariant) String() string {
+	if v < 0 || int(v) >= len(miVariantNames) {
+		return fmt.Sprintf("MIVariant(%d)", int(v))
+	}
+	return miVariantNames[v]
+}
+
+// Formula returns the formula of the variant, where V is the Halstead volume,
+// G the branch factor, L and S the numbers of physical and logical lines and C
+// the comment ratio.
+// Unknown variants are computed like MIMicrosoft (see Index).
+func (v MIVariant) Formula() string {
+	if v < 0 || int(v) >= len(miVariantFormulas) {
+		return miVariantFormulas[MIMicrosoft]
+	}
+	return miVariantFormulas[v]
+}
+
+// ParseMIVariant returns the variant with the given name.
+func ParseMIVariant(name string) (MIVariant, error) {
+	for v, s := range miVariantNames {
+		if s == name {
+			return MIVariant(v), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown maintainability index variant %q (want one of %s)", name, strings.Join(miVariantNames[:], ", "))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v MIVariant) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *MIVariant) UnmarshalText(text []byte) error {
+	variant, err := ParseMIVariant(string(text))
+	if err != nil {
+		return err
+	}
+	*v = variant
+	return nil
+}
+
+// Index returns the maintainability index for the Halstead volume, the branch
+// factor and the lines. Volume and lines of less than 1 count as 1, so empty
+// functions do not take the logarithm of 0.
+func (v MIVariant) Index(volume float64, branchFactor uint, lines Lines) float64 {
+	size := lines.Physical
+	if v == MILogical {
+		size = lines.Logical
+	}
+	mi := 171 - 5.2*math.Log(math.Max(volume, 1)) - 0.23*float64(branchFactor) -
+		16.2*math.Log(math.Max(float64(size), 1))
+	switch v {
+	case MIOriginal:
+		return mi
+	case MISEI:
+		return mi + 50*math.Sin(math.Sqrt(2.4*lines.CommentRatio()))
+	}
+	return math.Max(0, mi*100/171)
+}
+
+// funcLines counts the lines of fn, whose doc comment is doc (may be nil).
+func (w *walker) funcLines(fset *token.FileSet, fn ast.Node, doc *ast.CommentGroup) Lines {
+	start := fn.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	first, last := fset.Position(start), fset.Position(fn.End())
+	if last.Filename != first.Filename || last.Line < first.Line {
+		// cut off by a syntax error, so it ends with the file
+		if tf := fset.File(start); tf != nil {
+			last.Line = tf.LineCount()
+		}
+	}
+	var l Lines
+	l.Physical = last.Line - first.Line + 1
+	l.Comment = commentLines(fset, w.comments, start, fn.End())
+	w.inspect(fn, func(node ast.Node) bool {
+		if isCountedStmt(node) {
+			l.Logical++
+		}
+		return true
+	})
+	return l
+}
+
+// fileLines counts the lines of f.
+func fileLines(fset *token.FileSet, f *ast.File) Lines {
+	var l Lines
+	if tf := fset.File(f.Pos()); tf != nil {
+		l.Physical = tf.LineCount()
+	}
+	ast.Inspect(f, func(node ast.Node) bool {
+		if isCountedStmt(node) {
+			l.Logical++
+		}
+		return true
+	})
+	l.Comment = commentLines(fset, f.Comments, f.FileStart, f.FileEnd)
+	return l
+}
+
+// commentLines returns the number of lines between start and end holding a
+// comment.
+func commentLines(fset *token.FileSet, comments []*ast.CommentGroup, start, end token.Pos) int {
+	lines := make(map[int]bool)
+	for _, group := range comments {
+		for _, c := range group.List {
+			if c.Pos() < start || c.End() > end {
+				continue
+			}
+			for line := fset.Position(c.Pos()).Line; line <= fset.Position(c.End()).Line; line++ {
+				lines[line] = true
+			}
+		}
+	}
+	return len(lines)
+}
@@ -0,0 +1,104 @@
+package branch
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestMIVariant(t *testing.T) {
+	lines := Lines{Physical: 20, Logical: 12, Comment: 5}
+	mi := 171 - 5.2*math.Log(100) - 0.23*4 - 16.2*math.Log(20)
+
+	tests := []struct {
+		variant MIVariant
+		want    float64
+	}{
+		{MIMicrosoft, mi * 100 / 171},
+		{MIOriginal, mi},
+		{MISEI, mi + 50*math.Sin(math.Sqrt(2.4*0.25))},
+		{MILogical, (171 - 5.2*math.Log(100) - 0.23*4 - 16.2*math.Log(12)) * 100 / 171},
+	}
+	for _, test := range tests {
+		if got := test.variant.Index(100, 4, lines); !closeTo(got, test.want) {
+			t.Errorf("%v.Index() = %v, want %v\n", test.variant, got, test.want)
+		}
+		if v, err := ParseMIVariant(test.variant.String()); err != nil || v != test.variant {
+			t.Errorf("ParseMIVariant(%q) = %v, %v, want %v\n", test.variant.String(), v, err, test.variant)
+		}
+	}
+
+	// huge functions bottom out at 0 in the scaled variant only
+	huge := Lines{Physical: 100000}
+	if got := MIMicrosoft.Index(1e9, 500, huge); got != 0 {
+		t.Errorf("MIMicrosoft.Index(huge) = %v, want 0\n", got)
+	}
+	if got := MIOriginal.Index(1e9, 500, huge); got >= 0 {
+		t.Errorf("MIOriginal.Index(huge) = %v, want < 0\n", got)
+	}
+	if _, err := ParseMIVariant("ibm"); err == nil {
+		t.Errorf("ParseMIVariant(ibm) succeeded, want an error\n")
+	}
+
+	// unknown variants must not panic
+	for _, v := range []MIVariant{-1, 4} {
+		if got, want := v.String(), fmt.Sprintf("MIVariant(%d)", int(v)); got != want {
+			t.Errorf("MIVariant(%d).String() = %q, want %q\n", int(v), got, want)
+		}
+		if got, want := v.Formula(), MIMicrosoft.Formula(); got != want {
+			t.Errorf("MIVariant(%d).Formula() = %q, want %q\n", int(v), got, want)
+		}
+		if _, err := v.MarshalText(); err != nil {
+			t.Errorf("MIVariant(%d).MarshalText() returned error %v\n", int(v), err)
+		}
+	}
+}
+
+func TestMaintainability(t *testing.T) {
+	var test_code = `package main
+
+// documented has a doc comment
+// of two lines.
+func documented(x int) int {
+	// increment
+	x++
+	if x > 0 { // positive
+		return x
+	}
+	return 0
+}
+
+func empty() {
+}
+`
+	root := writeTree(t, map[string]string{"src.go": test_code})
+	pkgs, err := (&Config{Maintainability: MISEI}).AnalyzeDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := pkgs[0].Files[0]
+	if filepath.Base(file.Name) != "src.go" {
+		t.Fatalf("analyzed %v, want src.go\n", file.Name)
+	}
+
+	want := map[string]Lines{
+		"documented": {Physical: 10, Logical: 4, Comment: 4},
+		"empty":      {Physical: 2, Logical: 0, Comment: 0},
+	}
+	for _, fn := range file.Funcs {
+		if fn.Lines != want[fn.ID.Name] {
+			t.Errorf("Lines(%v) = %+v, want %+v\n", fn.ID.Name, fn.Lines, want[fn.ID.Name])
+		}
+		if mi := MISEI.Index(fn.Halstead.Volume, fn.BranchFactor, fn.Lines); fn.Maintainability != mi {
+			t.Errorf("Maintainability(%v) = %v, want %v\n", fn.ID.Name, fn.Maintainability, mi)
+		}
+	}
+
+	if want := (Lines{Physical: 15, Logical: 4, Comment: 4}); file.Lines != want {
+		t.Errorf("Lines(src.go) = %+v, want %+v\n", file.Lines, want)
+	}
+	if mi := MISEI.Index(file.HalsteadVolume, file.BranchFactor, file.Lines); file.Maintainability != mi {
+		t.Errorf("Maintainability(src.go) = %v, want %v\n", file.Maintainability, mi)
+	}
+}
@@ -0,0 +1,74 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Nesting describes how deeply the statements of a function are nested in
+// control structures (if, for, range, switch, type switch and select; an else
+// if counts as part of its if), see Branch.Depth.
+type Nesting struct {
+	// Max is the depth of the most deeply nested statement. The statements
+	// directly in the function body have depth 0.
+	Max int
+
+	// Deepest is the position of the first statement with depth Max. It is
+	// not valid if the function has no statements.
+	Deepest token.Position
+
+	// Profile holds the number of statements at each depth, so Profile[0] is
+	// the number of statements directly in the function body. Blocks and
+	// case clauses are not counted as statements of their own.
+	Profile []uint
+}
+
+// nesting computes the nesting of the statements in fn.
+func (w *walker) nesting(fset *token.FileSet, fn ast.Node) Nesting {
+	var n Nesting
+	var stack []ast.Node
+	depth := 0
+
+	w.inspect(fn, func(node ast.Node) bool {
+		if node == nil {
+			if w.nestsBranches(stack[len(stack)-1]) {
+				depth--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		if isCountedStmt(node) {
+			d := depth
+			if w.isElseIf(node) {
+				d-- // the else if is inside the if it belongs to
+			}
+			for len(n.Profile) <= d {
+				n.Profile = append(n.Profile, 0)
+			}
+			n.Profile[d]++
+			if d > n.Max || !n.Deepest.IsValid() {
+				n.Max = d
+				n.Deepest = fset.Position(node.Pos())
+			}
+		}
+		if w.nestsBranches(node) {
+			depth++
+		}
+		stack = append(stack, node)
+		return true
+	})
+
+	return n
+}
+
+// isCountedStmt reports whether node is a statement for the nesting profile.
+func isCountedStmt(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause, *ast.EmptyStmt:
+		return false
+	case ast.Stmt:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestNesting(t *testing.T) {
+	var test_code = `package main
+
+func empty() {
+}
+
+func flat(x int) {
+	x++
+	x--
+	if x > 0 {
+		x = 0
+	}
+}
+
+func arrow(xs [][]int) {
+	for _, row := range xs {
+		for _, x := range row {
+			if x > 0 {
+				switch x {
+				case 1:
+					println(x)
+				}
+			}
+		}
+	}
+	println("done")
+}
+
+func else_chain(x int) {
+	if x < 0 {
+		x = -x
+	} else if x > 0 {
+		x--
+	} else {
+		x++
+	}
+}
+`
+
+	tests := []struct {
+		name    string
+		max     int
+		line    int
+		profile []uint
+	}{
+		{"empty", 0, 0, nil},
+		{"flat", 1, 10, []uint{3, 1}},
+		{"arrow", 4, 20, []uint{2, 1, 1, 1, 1}},
+		{"else_chain", 1, 30, []uint{2, 3}},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		n := results[test.name].Nesting
+		if n.Max != test.max || n.Deepest.Line != test.line {
+			t.Errorf("nesting(%v) has max %d at line %d, want %d at line %d\n",
+				test.name, n.Max, n.Deepest.Line, test.max, test.line)
+		}
+		if len(n.Profile) != len(test.profile) {
+			t.Errorf("nesting(%v) has profile %v, want %v\n", test.name, n.Profile, test.profile)
+			continue
+		}
+		for d := range n.Profile {
+			if n.Profile[d] != test.profile[d] {
+				t.Errorf("nesting(%v) has profile %v, want %v\n", test.name, n.Profile, test.profile)
+				break
+			}
+		}
+	}
+
+	limits := &Limits{Metric: MetricNesting, Max: 3}
+	if v := limits.Check([]*Func{results["arrow"], results["flat"]}); len(v) != 1 || v[0].Value != 4 {
+		t.Errorf("Check(nesting <= 3) = %v, want only arrow with 4\n", v)
+	}
+}
@@ -0,0 +1,141 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+	"math/big"
+)
+
+// npath returns the NPath complexity of fn, the number of acyclic execution
+// paths through it, as defined by Nejmeh (1988):
+//
+//	a sequence of statements has the product of their NPaths, and every
+//	statement that does not branch has NPath 1
+//	if: NP(then) + NP(else) + B(cond), where a missing else has NPath 1
+//	for: NP(body) + B(cond) + 1
+//	range: NP(body) + 1
+//	switch and type switch: the sum of the NPaths of the clauses (plus 1 if
+//	there is no default) + B(tag)
+//	select: the sum of the NPaths of the clauses
+//	return: max(1, B(results))
+//
+// B(x) is the number of && and || in x. Since the number grows
+// multiplicatively with sequential decisions it is computed with big.Int.
+// Function literals are not looked into.
+func npath(fn ast.Node) *big.Int {
+	var body *ast.BlockStmt
+	switch f := fn.(type) {
+	case *ast.FuncDecl:
+		body = f.Body
+	case *ast.FuncLit:
+		body = f.Body
+	}
+	if body == nil {
+		return big.NewInt(1)
+	}
+	return npathList(body.List)
+}
+
+// npathList returns the NPath of a sequence of statements.
+func npathList(list []ast.Stmt) *big.Int {
+	n := big.NewInt(1)
+	for _, s := range list {
+		n.Mul(n, npathStmt(s))
+	}
+	return n
+}
+
+func npathStmt(s ast.Stmt) *big.Int {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		return npathList(s.List)
+
+	case *ast.LabeledStmt:
+		return npathStmt(s.Stmt)
+
+	case *ast.IfStmt:
+		n := npathList(s.Body.List)
+		if s.Else != nil {
+			n.Add(n, npathStmt(s.Else))
+		} else {
+			n.Add(n, big.NewInt(1))
+		}
+		return n.Add(n, boolOps(s.Cond))
+
+	case *ast.ForStmt:
+		n := npathList(s.Body.List)
+		n.Add(n, boolOps(s.Cond))
+		return n.Add(n, big.NewInt(1))
+
+	case *ast.RangeStmt:
+		n := npathList(s.Body.List)
+		return n.Add(n, big.NewInt(1))
+
+	case *ast.SwitchStmt:
+		n := npathClauses(s.Body)
+		return n.Add(n, boolOps(s.Tag))
+
+	case *ast.TypeSwitchStmt:
+		return npathClauses(s.Body)
+
+	case *ast.SelectStmt:
+		return npathClauses(s.Body)
+
+	case *ast.ReturnStmt:
+		n := big.NewInt(0)
+		for _, x := range s.Results {
+			n.Add(n, boolOps(x))
+		}
+		if n.Sign() == 0 {
+			n.SetInt64(1)
+		}
+		return n
+	}
+	return big.NewInt(1)
+}
+
+// npathClauses returns the sum of the NPaths of the case or communication
+// clauses in body. A switch without a default clause has one more path that
+// skips every clause; a select has none, since it waits until one of its
+// clauses runs. An empty select never continues, and counts as 1 like an
+// empty statement.
+func npathClauses(body *ast.BlockStmt) *big.Int {
+	n := big.NewInt(0)
+	hasDefault, isSelect := false, false
+	for _, c := range body.List {
+		switch cc := c.(type) {
+		case *ast.CaseClause:
+			n.Add(n, npathList(cc.Body))
+			hasDefault = hasDefault || cc.List == nil
+		case *ast.CommClause:
+			n.Add(n, npathList(cc.Body))
+			isSelect = true
+		}
+	}
+	if !hasDefault && !isSelect {
+		n.Add(n, big.NewInt(1))
+	}
+	if n.Sign() == 0 {
+		n.SetInt64(1)
+	}
+	return n
+}
+
+// boolOps returns the number of && and || operators in x, which may be nil.
+func boolOps(x ast.Expr) *big.Int {
+	var Count int64 = 0
+	if x != nil {
+		ast.Inspect(x, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.BinaryExpr:
+				if n.Op == token.LAND || n.Op == token.LOR {
+					Count++
+				}
+			}
+			return true
+		})
+	}
+	return big.NewInt(Count)
+}
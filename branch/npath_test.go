@@ -0,0 +1,124 @@
+package branch
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestNPath(t *testing.T) {
+	var test_code = `package main
+
+func empty() {
+}
+
+func one_if(x int) {
+	if x > 0 {
+		x = 0
+	}
+}
+
+func and_if(x, y int) {
+	if x > 0 && y > 0 {
+		x = 0
+	}
+}
+
+func two_ifs(x int) {
+	if x > 0 {
+		x = 0
+	}
+	if x < 0 {
+		x = 0
+	}
+}
+
+func if_else(x int) {
+	if x > 0 {
+		x = 0
+	} else if x < 0 {
+		x = 1
+	} else {
+		x = 2
+	}
+}
+
+func loops(xs []int) {
+	for i := 0; i < 10; i++ {
+		if xs[i] > 0 {
+			xs[i] = 0
+		}
+	}
+	for range xs {
+	}
+}
+
+func switches(x int) int {
+	switch x {
+	case 1:
+	case 2:
+		if x > 0 {
+			x = 0
+		}
+	}
+	switch {
+	case x > 0:
+	default:
+	}
+	return x
+}
+
+func returns(a, b, c bool) bool {
+	return a && b || c
+}
+
+func selects(c chan int) {
+	select {
+	case <-c:
+	case c <- 1:
+	}
+}
+
+func select_default(c chan int) {
+	select {
+	case <-c:
+	default:
+	}
+	select {}
+}
+`
+	want := map[string]int64{
+		"empty":          1,
+		"one_if":         2,
+		"and_if":         3,
+		"two_ifs":        4,
+		"if_else":        3,
+		"loops":          (2 + 0 + 1) * 2,
+		"switches":       (1 + 2 + 1) * 2,
+		"returns":        2,
+		"selects":        2, // no path skips both cases
+		"select_default": 2,
+	}
+	funcs := analyzeByName(t, test_code)
+	for name, value := range want {
+		if got := funcs[name].NPath; got.Cmp(big.NewInt(value)) != 0 {
+			t.Errorf("npath(%v) = %v, want %d\n", name, got, value)
+		}
+	}
+}
+
+func TestNPath_Overflow(t *testing.T) {
+	// 100 sequential ifs have 2^100 paths, which does not fit into a uint64
+	test_code := "package main\n\nfunc many(x int) {\n" +
+		strings.Repeat("\tif x > 0 {\n\t\tx--\n\t}\n", 100) + "}\n"
+
+	want := new(big.Int).Lsh(big.NewInt(1), 100)
+	funcs := analyzeByName(t, test_code)
+	fn := funcs["many"]
+	if fn.NPath.Cmp(want) != 0 {
+		t.Errorf("npath(many) = %v, want %v\n", fn.NPath, want)
+	}
+	if got := MetricNPath.Value(fn); got != ^uint(0) {
+		t.Errorf("MetricNPath.Value(many) = %d, want %d\n", got, ^uint(0))
+	}
+}
@@ -0,0 +1,202 @@
+package branch
+
+import (
+	"context"
+	"go/scanner"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// workers returns the number of goroutines analyzing files at the same time.
+func (c *Config) workers() int {
+	if c.Workers > 0 {
+		return c.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// pool bounds the number of goroutines of an analysis and stops it when its
+// context is done. A nil pool runs everything in the calling goroutine and is
+// never done.
+type pool struct {
+	ctx context.Context
+	sem chan struct{} // holds a token for every running goroutine
+}
+
+// newPool returns a pool of n goroutines.
+func newPool(ctx context.Context, n int) *pool {
+	return &pool{ctx: ctx, sem: make(chan struct{}, n)}
+}
+
+// err returns the error of the context of p once it is done.
+func (p *pool) err() error {
+	if p == nil {
+		return nil
+	}
+	return p.ctx.Err()
+}
+
+// do calls f(i) for i from 0 to n-1, each in a goroutine of its own while p
+// has a free token and in the calling goroutine otherwise, so do never waits
+// for a token and cannot deadlock when called from a goroutine of p. It stops
+// calling f once the context of p is done, and returns when all calls have
+// returned.
+func (p *pool) do(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if p == nil {
+			f(i)
+			continue
+		}
+		if p.ctx.Err() != nil {
+			break
+		}
+		select {
+		case p.sem <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-p.sem }()
+				f(i)
+			}(i)
+		default:
+			f(i)
+		}
+	}
+	wg.Wait()
+}
+
+// dirResult is the result of analyzing one directory of a tree.
+type dirResult struct {
+	pkgs []*Package
+	errs scanner.ErrorList
+	err  error
+}
+
+// AnalyzeTreeContext is like AnalyzeTree, but stops early with ctx.Err() when
+// ctx is done, which is checked between files. Up to c.Workers goroutines
+// work at the same time: each takes one directory after the other and parses
+// its files with the help of the goroutines that are idle, so a directory
+// with many files does not hold up the rest of the tree. The files of a
+// package are type-checked together. Since the results are sorted by package
+// path and the errors by position, they do not depend on the order in which
+// the goroutines finish.
+func (c *Config) AnalyzeTreeContext(ctx context.Context, root string) ([]*Package, error) {
+	c = c.config()
+	dirs, err := treeDirs(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return c.analyzeDirs(ctx, dirs, nil)
+}
+
+// AnalyzeDirContext is like AnalyzeDir, but parses the files in parallel and
+// stops early like AnalyzeTreeContext.
+func (c *Config) AnalyzeDirContext(ctx context.Context, dir string) ([]*Package, error) {
+	return c.config().analyzeDirs(ctx, []string{dir}, nil)
+}
+
+// AnalyzeFilesContext is like AnalyzeFiles, but analyzes the files in
+// parallel and stops early like AnalyzeTreeContext.
+func (c *Config) AnalyzeFilesContext(ctx context.Context, filenames ...string) ([]*Package, error) {
+	c = c.config()
+	byDir := make(map[string][]string)
+	var dirs []string
+	for _, name := range filenames {
+		dir, base := filepath.Split(name)
+		dir = filepath.Clean(dir)
+		if byDir[dir] == nil {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], base)
+	}
+	sort.Strings(dirs)
+	return c.analyzeDirs(ctx, dirs, byDir)
+}
+
+// analyzeDirs analyzes the packages in dirs with up to c.Workers goroutines,
+// see AnalyzeTreeContext. The files of a directory are those in files, or
+// its Go files (see goFiles) if it has no entry.
+func (c *Config) analyzeDirs(ctx context.Context, dirs []string, files map[string][]string) ([]*Package, error) {
+	results := make([]dirResult, len(dirs))
+	p := newPool(ctx, c.workers())
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < c.workers() && n < len(dirs); n++ {
+		// a worker holds its token until there are no more directories,
+		// and then leaves it to the parsing of the last ones
+		p.sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-p.sem }()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue // drain the jobs already sent
+				}
+				r := &results[i]
+				names, ok := files[dirs[i]]
+				if !ok {
+					if names, r.err = c.goFiles(dirs[i]); r.err != nil {
+						continue
+					}
+				}
+				r.pkgs = c.analyzePackages(p, dirs[i], names, &r.errs)
+			}
+		}()
+	}
+
+send:
+	for i := range dirs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// combine in the order of the directories, like a sequential walk
+	var pkgs []*Package
+	var errs scanner.ErrorList
+	for _, r := range results {
+		if r.err != nil {
+			return pkgs, r.err
+		}
+		pkgs = append(pkgs, r.pkgs...)
+		errs = append(errs, r.errs...)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Path < pkgs[j].Path })
+	errs.Sort()
+	return pkgs, errs.Err()
+}
+
+// treeDirs returns root and the directories below it that AnalyzeTree
+// analyzes, in lexical order.
+func treeDirs(ctx context.Context, root string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(root, func(dir string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if dir != root && skipDir(info.Name()) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, dir)
+		return nil
+	})
+	return dirs, err
+}
@@ -0,0 +1,127 @@
+package branch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/types"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAnalyzeTreeContext(t *testing.T) {
+	files := map[string]string{"go.mod": "module example.com/m\n"}
+	for i := 0; i < 40; i++ {
+		files[fmt.Sprintf("p%d/a.go", i)] = fmt.Sprintf(`package p%d
+
+func F%d(x int) {
+	for i := 0; i < x; i++ {
+		if i > %d {
+			break
+		}
+	}
+}
+`, i, i, i)
+	}
+	files["p7/broken.go"] = "package p7\n\nfunc Broken() {\n\tif {\n}\n"
+	files["p23/broken.go"] = "package p23\n\nfunc Broken( {\n}\n"
+	root := writeTree(t, files)
+
+	// the output of one worker is the reference
+	report := func(workers int) string {
+		pkgs, err := (&Config{Workers: workers}).AnalyzeTreeContext(context.Background(), root)
+		if _, ok := err.(scanner.ErrorList); !ok {
+			t.Fatalf("AnalyzeTreeContext(workers=%d) returned error %v, want the syntax errors\n", workers, err)
+		}
+		var buf bytes.Buffer
+		fmt.Fprintln(&buf, err)
+		if err := (&Report{Funcs: Funcs(pkgs)}).WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	want := report(1)
+	for _, workers := range []int{2, 8, 64, 0} {
+		for run := 0; run < 5; run++ {
+			if got := report(workers); got != want {
+				t.Fatalf("AnalyzeTreeContext(workers=%d) wrote\n%s\nwant\n%s", workers, got, want)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if pkgs, err := (&Config{Workers: 4}).AnalyzeTreeContext(ctx, root); err != context.Canceled || pkgs != nil {
+		t.Errorf("AnalyzeTreeContext(canceled) = %d packages, %v, want none and %v\n", len(pkgs), err, context.Canceled)
+	}
+	if pkgs, err := (&Config{Workers: 4}).AnalyzeDirContext(ctx, filepath.Join(root, "p1")); err != context.Canceled || pkgs != nil {
+		t.Errorf("AnalyzeDirContext(canceled) = %d packages, %v, want none and %v\n", len(pkgs), err, context.Canceled)
+	}
+	if pkgs, err := (&Config{Workers: 4}).AnalyzeFilesContext(ctx, filepath.Join(root, "p1", "a.go")); err != context.Canceled || pkgs != nil {
+		t.Errorf("AnalyzeFilesContext(canceled) = %d packages, %v, want none and %v\n", len(pkgs), err, context.Canceled)
+	}
+}
+
+// cancelImporter cancels the analysis the first time a package is imported.
+type cancelImporter struct {
+	cancel  context.CancelFunc
+	imports int32
+}
+
+func (imp *cancelImporter) Import(path string) (*types.Package, error) {
+	atomic.AddInt32(&imp.imports, 1)
+	imp.cancel()
+	return nil, errors.New("canceled")
+}
+
+func TestAnalyzeTreeContextCancel(t *testing.T) {
+	files := make(map[string]string)
+	for i := 0; i < 40; i++ {
+		files[fmt.Sprintf("p%d/a.go", i)] = fmt.Sprintf("package p%d\n\nimport \"fmt\"\n\nfunc F() { fmt.Println() }\n", i)
+	}
+	root := writeTree(t, files)
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		imp := &cancelImporter{cancel: cancel}
+		pkgs, err := (&Config{Workers: workers, Importer: imp}).AnalyzeTreeContext(ctx, root)
+		if err != context.Canceled || pkgs != nil {
+			t.Errorf("AnalyzeTreeContext(workers=%d, canceled midway) = %d packages, %v, want none and %v\n", workers, len(pkgs), err, context.Canceled)
+		}
+		// only the packages already being type-checked import anything
+		if n := int(atomic.LoadInt32(&imp.imports)); n > workers {
+			t.Errorf("AnalyzeTreeContext(workers=%d, canceled midway) type-checked %d packages, want at most %d\n", workers, n, workers)
+		}
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newPool(ctx, 1)
+	p.sem <- struct{}{} // no free token, so do calls f itself
+
+	var calls []int
+	p.do(10, func(i int) {
+		calls = append(calls, i)
+		if i == 2 {
+			cancel()
+		}
+	})
+	if len(calls) != 3 {
+		t.Errorf("pool.do(10) after canceling in call 2 made calls %v, want [0 1 2]\n", calls)
+	}
+	if p.err() != context.Canceled {
+		t.Errorf("pool.err() = %v, want %v\n", p.err(), context.Canceled)
+	}
+
+	// a nil pool calls f for every index
+	n := 0
+	(*pool)(nil).do(10, func(int) { n++ })
+	if n != 10 {
+		t.Errorf("(*pool)(nil).do(10) made %d calls, want 10\n", n)
+	}
+}
@@ -0,0 +1,171 @@
+package branch
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"io"
+	"sort"
+)
+
+// Policy decides which statements count as branches and how much each of them
+// adds to the branch factor.
+type Policy struct {
+	// Name names the policy in reports.
+	Name string `json:"name"`
+
+	// Weights maps each kind of statement to what one statement of that kind
+	// adds to the branch factor. Kinds that are missing or have weight 0 do
+	// not count.
+	Weights map[Kind]uint `json:"weights"`
+}
+
+// The named policies, see PolicyByName.
+var (
+	// DefaultPolicy counts every if (including else if), for, range, switch,
+	// type switch, goto, break, continue and fallthrough once, which is what
+	// the branch factor has always been.
+	DefaultPolicy = &Policy{
+		Name: "default",
+		Weights: map[Kind]uint{
+			KindIf: 1, KindElseIf: 1, KindFor: 1, KindRange: 1,
+			KindSwitch: 1, KindTypeSwitch: 1,
+			KindGoto: 1, KindBreak: 1, KindContinue: 1, KindFallthrough: 1,
+		},
+	}
+
+	// ConcurrentPolicy is DefaultPolicy plus every select statement and each
+	// of its communication clauses (but not default), for code whose
+	// branching is mostly done by select.
+	ConcurrentPolicy = &Policy{
+		Name: "concurrent",
+		Weights: map[Kind]uint{
+			KindIf: 1, KindElseIf: 1, KindFor: 1, KindRange: 1,
+			KindSwitch: 1, KindTypeSwitch: 1, KindSelect: 1, KindCommClause: 1,
+			KindGoto: 1, KindBreak: 1, KindContinue: 1, KindFallthrough: 1,
+		},
+	}
+
+	// StructuredPolicy only counts the control structures, including select,
+	// and leaves out the jump statements.
+	StructuredPolicy = &Policy{
+		Name: "structured",
+		Weights: map[Kind]uint{
+			KindIf: 1, KindElseIf: 1, KindFor: 1, KindRange: 1,
+			KindSwitch: 1, KindTypeSwitch: 1, KindSelect: 1,
+		},
+	}
+
+	// StrictPolicy counts every kind, including select, its communication
+	// clauses and defer, and counts goto twice.
+	StrictPolicy = &Policy{
+		Name: "strict",
+		Weights: map[Kind]uint{
+			KindIf: 1, KindElseIf: 1, KindFor: 1, KindRange: 1,
+			KindSwitch: 1, KindTypeSwitch: 1, KindSelect: 1, KindCommClause: 1,
+			KindDefer: 1, KindGoto: 2, KindBreak: 1, KindContinue: 1, KindFallthrough: 1,
+		},
+	}
+)
+
+var policies = []*Policy{DefaultPolicy, ConcurrentPolicy, StructuredPolicy, StrictPolicy}
+
+// PolicyByName returns the named policy: "default", "concurrent",
+// "structured" or "strict".
+func PolicyByName(name string) (*Policy, error) {
+	var names []string
+	for _, p := range policies {
+		if p.Name == name {
+			return p, nil
+		}
+		names = append(names, p.Name)
+	}
+	return nil, fmt.Errorf("unknown policy %q (want one of %v)", name, names)
+}
+
+// ReadPolicy reads a policy from a JSON config file like
+//
+//	{
+//		"name": "our-team",
+//		"base": "structured",
+//		"weights": {"goto": 3, "elseif": 0}
+//	}
+//
+// The weights are applied on top of the named base policy (the default policy
+// if there is no base); a weight of 0 disables a kind.
+func ReadPolicy(r io.Reader) (*Policy, error) {
+	var config struct {
+		Name    string
+		Base    string
+		Weights map[Kind]uint
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("reading policy: %v", err)
+	}
+
+	base := DefaultPolicy
+	if config.Base != "" {
+		var err error
+		if base, err = PolicyByName(config.Base); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Policy{Name: config.Name, Weights: make(map[Kind]uint)}
+	if p.Name == "" {
+		p.Name = base.Name + "+custom"
+	}
+	for kind, weight := range base.Weights {
+		p.Weights[kind] = weight
+	}
+	for kind, weight := range config.Weights {
+		if weight == 0 {
+			delete(p.Weights, kind)
+		} else {
+			p.Weights[kind] = weight
+		}
+	}
+	return p, nil
+}
+
+// String formats the policy as its name followed by the weights of the kinds
+// that count, e.g. "default(if=1 for=1 ...)".
+func (p *Policy) String() string {
+	var kinds []int
+	for kind := range p.Weights {
+		kinds = append(kinds, int(kind))
+	}
+	sort.Ints(kinds)
+
+	s := p.Name + "("
+	for i, kind := range kinds {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%s=%d", Kind(kind), p.Weights[Kind(kind)])
+	}
+	return s + ")"
+}
+
+// count returns the kind of node and what it adds to the branch factor, which
+// is 0 if it is no branching statement, if the policy does not count its kind
+// or if it is left out as a constant condition.
+func (w *walker) count(node ast.Node) (Kind, uint) {
+	kind, ok := w.kind(node)
+	if !ok || w.isConstant(node) {
+		return kind, 0
+	}
+	return kind, w.weight(kind)
+}
+
+// weight returns what a statement of the given kind adds to the branch factor
+// under the policy of the walker.
+func (w *walker) weight(kind Kind) uint {
+	p := w.policy
+	if p == nil {
+		p = DefaultPolicy
+	}
+	return p.Weights[kind]
+}
@@ -0,0 +1,95 @@
+package branch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicies(t *testing.T) {
+	var test_code = `package main
+
+func mixed(x int, c chan int) {
+	defer close(c)
+	if x > 0 {
+		goto end
+	} else if x < 0 {
+		return
+	}
+	for {
+		select {
+		case <-c:
+			break
+		}
+	}
+end:
+}
+`
+
+	tests := []struct {
+		policy *Policy
+		want   uint
+	}{
+		{DefaultPolicy, 5},    // if, else if, goto, for, break
+		{ConcurrentPolicy, 7}, // if, else if, goto, for, select, case, break
+		{StructuredPolicy, 4}, // if, else if, for, select
+		{StrictPolicy, 9},     // defer, if, else if, goto (2), for, select, case, break
+		{&Policy{Name: "loops", Weights: map[Kind]uint{KindFor: 3, KindRange: 3}}, 3},
+	}
+
+	for _, test := range tests {
+		funcs, err := (&Config{Policy: test.policy}).AnalyzeSource("src.go", test_code)
+		if err != nil {
+			t.Fatalf("AnalyzeSource returned error %v\n", err)
+		}
+		fn := funcs[0]
+		if fn.BranchFactor != test.want {
+			t.Errorf("branchCount(mixed) under %v = %d, want %d\n", test.policy, fn.BranchFactor, test.want)
+		}
+		if fn.Breakdown.Total != fn.BranchFactor {
+			t.Errorf("breakdown(mixed) under %v has total %d, want %d\n", test.policy.Name, fn.Breakdown.Total, fn.BranchFactor)
+		}
+	}
+}
+
+func TestPolicyByName(t *testing.T) {
+	for _, name := range []string{"default", "structured", "strict"} {
+		if p, err := PolicyByName(name); err != nil || p.Name != name {
+			t.Errorf("PolicyByName(%q) = %v, %v\n", name, p, err)
+		}
+	}
+	if _, err := PolicyByName("lenient"); err == nil {
+		t.Errorf("PolicyByName(\"lenient\") did not return an error\n")
+	}
+}
+
+func TestReadPolicy(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{`{}`, "default+custom(if=1 for=1 range=1 switch=1 typeswitch=1 goto=1 break=1 continue=1 fallthrough=1 elseif=1)"},
+		{`{"name": "team", "base": "structured", "weights": {"goto": 3, "elseif": 0}}`,
+			"team(if=1 for=1 range=1 switch=1 typeswitch=1 goto=3 select=1)"},
+	}
+	for _, test := range tests {
+		p, err := ReadPolicy(strings.NewReader(test.config))
+		if err != nil {
+			t.Errorf("ReadPolicy(%s) returned error %v\n", test.config, err)
+			continue
+		}
+		if p.String() != test.want {
+			t.Errorf("ReadPolicy(%s) = %v, want %v\n", test.config, p, test.want)
+		}
+	}
+
+	for _, config := range []string{
+		`{"base": "lenient"}`,
+		`{"weights": {"loop": 1}}`,
+		`{"wieghts": {}}`,
+		`not json`,
+	} {
+		if _, err := ReadPolicy(strings.NewReader(config)); err == nil {
+			t.Errorf("ReadPolicy(%s) did not return an error\n", config)
+		}
+	}
+}
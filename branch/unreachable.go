@@ -0,0 +1,100 @@
+package branch
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Unreachable returns the positions of the statements of the function fn (a
+// *ast.FuncDecl or *ast.FuncLit) that can never run, found with its
+// control-flow graph: code after a return, a call to panic, a goto, break or
+// continue, code after an infinite for loop without break or an empty select,
+// and the arms of if and for statements whose condition is the constant true
+// or false. Named constants are recognized if eval is not nil, see BuildCFG.
+//
+// Only the first statement of each unreachable run of statements is reported,
+// not the ones after it or inside it. Function literals are not looked into.
+func Unreachable(fset *token.FileSet, fn ast.Node, eval ConstEval) []token.Position {
+	var list []token.Position
+	for _, s := range unreachableStmts(BuildCFG(fn, eval), fn) {
+		list = append(list, fset.Position(s.Pos()))
+	}
+	return list
+}
+
+// unreachableStmts returns the first statement of each unreachable run of
+// statements in the function fn with the graph g.
+func unreachableStmts(g *CFG, fn ast.Node) []ast.Stmt {
+	var body *ast.BlockStmt
+	switch f := fn.(type) {
+	case *ast.FuncDecl:
+		body = f.Body
+	case *ast.FuncLit:
+		body = f.Body
+	}
+	if body == nil {
+		return nil
+	}
+
+	reachable := g.Reachable()
+	live := func(s ast.Stmt) bool {
+		block := g.stmts[s]
+		return block == nil || reachable[block.Index]
+	}
+
+	var dead []ast.Stmt
+	// visit visits the statements of list, where the statement before the
+	// first one (or the statement list is part of) is live if prevLive is set
+	var visit func(list []ast.Stmt, prevLive bool)
+	visit = func(list []ast.Stmt, prevLive bool) {
+		for _, s := range list {
+			if block, ok := s.(*ast.BlockStmt); ok {
+				visit(block.List, prevLive)
+				prevLive = live(s)
+				continue
+			}
+
+			sLive := live(s)
+			if !sLive && prevLive {
+				dead = append(dead, s)
+			}
+			prevLive = sLive
+
+			switch s := s.(type) {
+			case *ast.LabeledStmt:
+				visit([]ast.Stmt{s.Stmt}, sLive)
+			case *ast.IfStmt:
+				visit(s.Body.List, sLive)
+				if s.Else != nil {
+					visit([]ast.Stmt{s.Else}, sLive)
+				}
+			case *ast.ForStmt:
+				visit(s.Body.List, sLive)
+			case *ast.RangeStmt:
+				visit(s.Body.List, sLive)
+			case *ast.SwitchStmt:
+				visitClauses(s.Body, sLive, visit)
+			case *ast.TypeSwitchStmt:
+				visitClauses(s.Body, sLive, visit)
+			case *ast.SelectStmt:
+				visitClauses(s.Body, sLive, visit)
+			}
+		}
+	}
+	visit(body.List, true)
+
+	return dead
+}
+
+// visitClauses calls visit for the statements of each case or communication
+// clause of body.
+func visitClauses(body *ast.BlockStmt, live bool, visit func([]ast.Stmt, bool)) {
+	for _, c := range body.List {
+		switch cc := c.(type) {
+		case *ast.CaseClause:
+			visit(cc.Body, live)
+		case *ast.CommClause:
+			visit(cc.Body, live)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package branch
+
+import (
+	"testing"
+)
+
+func TestUnreachable(t *testing.T) {
+	var test_code = `package main
+
+func clean(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func after_return(x int) int {
+	return x
+	x++
+	x--
+}
+
+func after_panic() {
+	panic("boom")
+	println("never")
+}
+
+func after_goto(x int) {
+	goto end
+	x++
+end:
+	println(x)
+}
+
+func after_loop() {
+	for {
+		println("forever")
+	}
+	println("never")
+}
+
+func loop_with_break(x int) {
+	for {
+		if x > 0 {
+			break
+		}
+	}
+	println("reached")
+}
+
+func constant_conditions(x int) {
+	if false {
+		x++
+	}
+	if true {
+		x--
+	} else {
+		x = 0
+		x++
+	}
+	for false {
+		x--
+	}
+	if !true || false {
+		x = 1
+	}
+}
+
+func in_switch(x int) {
+	switch x {
+	case 1:
+		return
+		x++
+	case 2:
+		break
+		x--
+	}
+}
+
+func after_break(xs []int) {
+	for range xs {
+		continue
+		println("never")
+	}
+}
+
+func after_select() {
+	select {}
+	println("never")
+}
+
+const debug = false
+
+func named_constants(x int) {
+	if debug {
+		println()
+	}
+	if x > 0 || !debug {
+		return
+	}
+	x++
+}
+`
+
+	tests := []struct {
+		name  string
+		lines []int
+	}{
+		{"clean", nil},
+		{"after_return", []int{12}},
+		{"after_panic", []int{18}},
+		{"after_goto", []int{23}},
+		{"after_loop", []int{32}},
+		{"loop_with_break", nil},
+		{"constant_conditions", []int{46, 51, 55, 58}},
+		{"in_switch", []int{66, 69}},
+		{"after_break", []int{76}},
+		{"after_select", []int{82}},
+		{"named_constants", []int{89, 94}},
+	}
+
+	results := analyzeByName(t, test_code)
+	for _, test := range tests {
+		var got []int
+		for _, pos := range results[test.name].Unreachable {
+			got = append(got, pos.Line)
+		}
+		if len(got) != len(test.lines) {
+			t.Errorf("Unreachable(%v) = lines %v, want %v\n", test.name, got, test.lines)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.lines[i] {
+				t.Errorf("Unreachable(%v) = lines %v, want %v\n", test.name, got, test.lines)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package branchanalysis provides a go/analysis Analyzer that reports
+// functions over their branch factor (or another complexity metric) limit,
+// so the check runs under "go vet -vettool" and in multichecker binaries
+// next to other analyzers.
+//
+// The analyzer exports a Fact with the metrics of every function declaration,
+// which analyzers depending on it can read for functions of imported packages.
+package branchanalysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"hw2/branch"
+)
+
+// Analyzer reports the functions exceeding their limit and problems with
+// //branch: directives, see branch.Limits and branch.Directive.
+var Analyzer = &analysis.Analyzer{
+	Name:      "branchfactor",
+	Doc:       "report functions whose branch factor (or another complexity metric) exceeds a limit",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(FuncFact)},
+}
+
+// The flags of the analyzer. They are prefixed with the analyzer name in
+// multichecker binaries, e.g. -branchfactor.max=15. Like cmd/branchfactor,
+// the analyzer has no limit by default and only exports facts and checks
+// directives until -max is set.
+var (
+	maxFlag     uint
+	metricName  string
+	policyName  string
+	excludeLits bool
+	excludeCons bool
+)
+
+func init() {
+	Analyzer.Flags.UintVar(&maxFlag, "max", 0, "largest allowed value of the metric per function (0: no limit)")
+	Analyzer.Flags.StringVar(&metricName, "metric", "branch", "metric to limit: branch, cyclomatic, cognitive, concurrency, nesting or npath")
+	Analyzer.Flags.StringVar(&policyName, "policy", "default", "counting policy: default, concurrent, structured or strict")
+	Analyzer.Flags.BoolVar(&excludeLits, "exclude-funclits", false, "do not charge branches of function literals to the enclosing function")
+	Analyzer.Flags.BoolVar(&excludeCons, "exclude-constant", false, "do not count if, for and switch statements with constant conditions")
+}
+
+// FuncFact holds the metrics of a function declaration.
+type FuncFact struct {
+	BranchFactor uint
+	Cyclomatic   uint
+	Cognitive    uint
+	Concurrency  uint
+	MaxNesting   int
+}
+
+// AFact implements analysis.Fact.
+func (*FuncFact) AFact() {}
+
+// String implements the Stringer interface for FuncFact; it is how facts are
+// printed in tests and with the -debug flag.
+func (f *FuncFact) String() string {
+	return fmt.Sprintf("branch=%d cyclomatic=%d cognitive=%d concurrency=%d nesting=%d",
+		f.BranchFactor, f.Cyclomatic, f.Cognitive, f.Concurrency, f.MaxNesting)
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	metric, err := branch.ParseMetric(metricName)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := branch.PolicyByName(policyName)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &branch.Config{Policy: policy, ExcludeFuncLits: excludeLits, ExcludeConstant: excludeCons}
+	pkg := cfg.AnalyzeSyntax(pass.Fset, pass.Pkg.Path(), pass.Files, pass.TypesInfo)
+	funcs := branch.Funcs([]*branch.Package{pkg})
+
+	// the results only carry positions, so map them back to the declarations
+	decls := make(map[token.Position]*ast.FuncDecl)
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				decls[pass.Fset.Position(fn.Pos())] = fn
+			}
+		}
+	}
+
+	for _, fn := range funcs {
+		decl := decls[fn.Pos]
+		if decl == nil {
+			continue
+		}
+		if obj, ok := pass.TypesInfo.Defs[decl.Name].(*types.Func); ok {
+			pass.ExportObjectFact(obj, &FuncFact{
+				BranchFactor: fn.BranchFactor,
+				Cyclomatic:   fn.Cyclomatic,
+				Cognitive:    fn.Cognitive,
+				Concurrency:  fn.Concurrency,
+				MaxNesting:   fn.Nesting.Max,
+			})
+		}
+	}
+
+	limits := &branch.Limits{Metric: metric, Max: maxFlag}
+	for _, v := range limits.Check(funcs) {
+		if decl := decls[v.Func.Pos]; decl != nil {
+			pass.Reportf(decl.Pos(), "%s has a %s of %d, which exceeds the limit of %d",
+				v.Func.ID.Local(), v.Metric.Description(), v.Value, v.Limit)
+		}
+	}
+	for _, w := range limits.Warnings(funcs) {
+		if decl := decls[w.Func.Pos]; decl != nil && decl.Doc != nil {
+			pass.Report(analysis.Diagnostic{
+				Pos:      directivePos(pass.Fset, decl.Doc, w.Directive),
+				Category: "directive",
+				Message:  fmt.Sprintf("//%s: %s", w.Directive.Text, w.Msg),
+			})
+		}
+	}
+	return nil, nil
+}
+
+// directivePos returns the position of the comment of the directive d in doc.
+func directivePos(fset *token.FileSet, doc *ast.CommentGroup, d branch.Directive) token.Pos {
+	for _, c := range doc.List {
+		if fset.Position(c.Pos()) == d.Pos {
+			return c.Pos()
+		}
+	}
+	return doc.Pos()
+}
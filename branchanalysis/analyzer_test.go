@@ -0,0 +1,15 @@
+package branchanalysis
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	if err := Analyzer.Flags.Set("max", "3"); err != nil {
+		t.Fatal(err)
+	}
+	defer Analyzer.Flags.Set("max", "0")
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
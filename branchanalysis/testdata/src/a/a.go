@@ -0,0 +1,35 @@
+package a
+
+func Simple(x int) { // want Simple:"branch=1 cyclomatic=2 cognitive=1 concurrency=0 nesting=0"
+	if x > 0 {
+	}
+}
+
+func Complex(x int) { // want `Complex has a branch factor of 4, which exceeds the limit of 3` Complex:"branch=4 cyclomatic=5 cognitive=4 concurrency=0 nesting=0"
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+	if x > 3 {
+	}
+}
+
+// Intended is a state machine.
+//
+//branch:max=5 one case per state
+func Intended(x int) { // want Intended:"branch=4 cyclomatic=5 cognitive=4 concurrency=0 nesting=0"
+	if x > 0 {
+	}
+	if x > 1 {
+	}
+	if x > 2 {
+	}
+	if x > 3 {
+	}
+}
+
+/* want `//branch:ignore: directive has no reason` `//branch:ignore: no longer needed` */ //branch:ignore
+func Stale() /* want Stale:"branch=0 cyclomatic=1 cognitive=0 concurrency=0 nesting=0" */ {
+}
@@ -0,0 +1,438 @@
+// Command branchfactor reports the branch factor (or another complexity
+// metric) of every function in Go source and fails if a function exceeds a
+// limit, so it can gate merges in CI.
+//
+// Usage:
+//
+//	branchfactor [flags] [file.go | dir | dir/...]...
+//
+// Without arguments the current directory is analyzed. Besides the default
+// text table, the report can be written as JSON, CSV or SARIF (-format). Every
+// report includes the maintainability index of the functions; -mi selects
+// its formula, which is printed along with it.
+//
+// Which statements count towards the branch factor is set by a named policy
+// (-policy) or a JSON policy file (-policy-file), see branch.ReadPolicy. A
+// "dir/..." argument analyzes dir and all directories below it, e.g. "./...".
+//
+// A function can be exempted from its limit by a "//branch:ignore reason" or
+// "//branch:max=N reason" line in its doc comment. Directives without a reason
+// or that are no longer needed are reported as warnings, which do not change
+// the exit status.
+//
+// Legacy code that already exceeds the limit can be checked against a baseline
+// file written with -write-baseline: with -baseline, only new functions and
+// functions whose value grew beyond the baseline fail.
+//
+// With -base the arguments are compared to a base version of the code (e.g. a
+// checkout of the target branch) instead, and the functions that were added,
+// removed, or whose metric increased or decreased are reported.
+//
+// With -cache, the results of every analyzed directory are kept on disk and
+// reused as long as its files, the settings and the version of the analysis
+// do not change; the hits and misses are printed to standard error.
+//
+// The exit status is 0 if every function is within its limit, 1 if some
+// function exceeds it and 2 if the source could not be analyzed. With -base
+// the status is 1 only if the change makes things worse: a function's metric
+// increased, or a new function exceeds its limit.
+//
+// A file with syntax errors does not stop the run: every error is printed,
+// the functions that could be parsed are still reported and checked, and the
+// exit status is 2 in the end.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"hw2/branch"
+)
+
+// packageLimits implements flag.Value for repeated -pkg-max path=N flags.
+type packageLimits map[string]uint
+
+func (p packageLimits) String() string {
+	var list []string
+	for path, max := range p {
+		list = append(list, fmt.Sprintf("%s=%d", path, max))
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func (p packageLimits) Set(s string) error {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return fmt.Errorf("want path=N, got %q", s)
+	}
+	max, err := strconv.ParseUint(s[i+1:], 10, 0)
+	if err != nil {
+		return fmt.Errorf("bad limit in %q: %v", s, err)
+	}
+	p[s[:i]] = uint(max)
+	return nil
+}
+
+// stringList implements flag.Value for repeated string flags.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command with the arguments args and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("branchfactor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		metricName = flags.String("metric", "branch", "metric to report and limit: branch, cyclomatic, cognitive, concurrency, nesting or npath")
+		max        = flags.Uint("max", 0, "largest allowed `value` of the metric per function (0: no limit)")
+		tests      = flags.Bool("tests", false, "include _test.go files")
+		funcLits   = flags.Bool("funclits", false, "report function literals as functions of their own")
+		exclLits   = flags.Bool("exclude-funclits", false, "do not charge branches of function literals to the enclosing function")
+		exclConst  = flags.Bool("exclude-constant", false, "do not count if, for and switch statements with constant conditions")
+		quiet      = flags.Bool("q", false, "only print the functions exceeding their limit")
+		format     = flags.String("format", "text", "output format: text, json, csv or sarif")
+		policyName = flags.String("policy", "default", "counting policy: default, concurrent, structured or strict")
+		policyFile = flags.String("policy-file", "", "read the counting policy from the JSON `file` (overrides -policy)")
+		baseline   = flags.String("baseline", "", "only fail on functions that are new or grew beyond the baseline `file`")
+		writeBase  = flags.String("write-baseline", "", "write the current value of every function to the baseline `file` and exit")
+		miName     = flags.String("mi", "microsoft", "maintainability index formula: microsoft, original, sei or logical")
+		workers    = flags.Int("j", 0, "analyze with up to `n` goroutines in parallel (0: one per CPU)")
+		cacheDir   = flags.String("cache", "", "keep the results of unchanged directories in the cache `dir`")
+	)
+	pkgMax := make(packageLimits)
+	flags.Var(pkgMax, "pkg-max", "limit for a package, as `path=N` or path/...=N (repeatable)")
+	var base stringList
+	flags.Var(&base, "base", "compare to the base version of the code in `file.go, dir or dir/...` (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: branchfactor [flags] [file.go | dir | dir/...]...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	metric, err := branch.ParseMetric(*metricName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	policy, err := readPolicy(*policyName, *policyFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	variant, err := branch.ParseMIVariant(*miName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	cfg := &branch.Config{
+		Tests:           *tests,
+		FuncLits:        *funcLits,
+		ExcludeFuncLits: *exclLits,
+		Policy:          policy,
+		ExcludeConstant: *exclConst,
+		Maintainability: variant,
+		Workers:         *workers,
+	}
+	if *cacheDir != "" {
+		if cfg.Cache, err = branch.OpenCache(*cacheDir); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	limits := &branch.Limits{Metric: metric, Max: *max, Packages: pkgMax}
+
+	// an interrupt stops the analysis instead of killing the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// syntax errors are reported, but the functions that could be parsed
+	// are still checked; the exit status is 2 in the end
+	pkgs, syntaxErrs, err := analyze(ctx, cfg, flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if cfg.Cache != nil {
+		defer func() { fmt.Fprintln(stderr, cfg.Cache.Stats()) }()
+	}
+
+	funcs := branch.Funcs(pkgs)
+	sortFuncs(funcs, metric)
+	report := &branch.Report{
+		Funcs:           funcs,
+		Violations:      limits.Check(funcs),
+		Warnings:        limits.Warnings(funcs),
+		Policy:          policy,
+		Maintainability: variant,
+	}
+	for _, pkg := range pkgs {
+		report.Files = append(report.Files, pkg.Files...)
+	}
+
+	if *writeBase != "" {
+		if len(syntaxErrs) > 0 {
+			// a baseline without the broken functions would report them
+			// as new once they are fixed
+			scanner.PrintError(stderr, syntaxErrs)
+			return 2
+		}
+		b, err := branch.NewBaseline(funcs, metric, policy)
+		if err == nil {
+			err = writeBaseline(*writeBase, b)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprintf(stdout, "wrote baseline of %d functions to %s\n", len(funcs), *writeBase)
+		return 0
+	}
+	if *baseline != "" {
+		b, err := readBaseline(*baseline, metric, policy)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		if report.Violations, err = b.Check(limits, funcs); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	if len(base) > 0 {
+		basePkgs, baseErrs, err := analyze(ctx, cfg, base)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		syntaxErrs = append(syntaxErrs, baseErrs...)
+		if report.Changes, err = branch.Diff(branch.Funcs(basePkgs), funcs, metric); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	switch {
+	case len(base) > 0 && *format == "text":
+		printChanges(stdout, report.Changes, limits, *quiet)
+	case len(base) > 0 && *format != "json":
+		err = fmt.Errorf("format %q does not support -base (want text or json)", *format)
+	case *format == "text":
+		printText(stdout, report, metric, *quiet)
+	case *format == "json":
+		err = report.WriteJSON(stdout)
+	case *format == "csv":
+		err = report.WriteCSV(stdout)
+	case *format == "sarif":
+		err = report.WriteSARIF(stdout)
+	default:
+		err = fmt.Errorf("unknown format %q (want text, json, csv or sarif)", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if len(syntaxErrs) > 0 {
+		scanner.PrintError(stderr, syntaxErrs)
+		return 2
+	}
+	if len(base) > 0 {
+		for _, c := range report.Changes {
+			if c.Worse(limits) {
+				return 1
+			}
+		}
+		return 0
+	}
+	if len(report.Violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printChanges prints the changes to the base version, marking the ones that
+// make things worse, or only those if quiet is set.
+func printChanges(w io.Writer, changes []branch.Change, limits *branch.Limits, quiet bool) {
+	for _, c := range changes {
+		switch {
+		case c.Worse(limits):
+			fmt.Fprintf(w, "%s (worse)\n", c)
+		case !quiet:
+			fmt.Fprintln(w, c)
+		}
+	}
+}
+
+// printText prints the report as a table followed by the violations and the
+// warnings, or only the violations and warnings if quiet is set.
+func printText(w io.Writer, report *branch.Report, metric branch.Metric, quiet bool) {
+	if !quiet {
+		fmt.Fprintf(w, "policy: %s\n", report.Policy)
+		fmt.Fprintf(w, "maintainability index (%s): %s\n\n", report.Maintainability, report.Maintainability.Formula())
+		printTable(w, report.Funcs, metric)
+		if len(report.Violations) > 0 || len(report.Warnings) > 0 {
+			fmt.Fprintln(w)
+		}
+	}
+	for _, v := range report.Violations {
+		fmt.Fprintln(w, v)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(w, warning)
+	}
+}
+
+// readPolicy returns the policy read from file, or the named policy if file is
+// empty.
+func readPolicy(name, file string) (*branch.Policy, error) {
+	if file == "" {
+		return branch.PolicyByName(name)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return branch.ReadPolicy(f)
+}
+
+// readBaseline reads the baseline file and makes sure it was written for the
+// metric and policy, otherwise the values cannot be compared.
+func readBaseline(file string, metric branch.Metric, policy *branch.Policy) (*branch.Baseline, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := branch.ReadBaseline(f)
+	if err != nil {
+		return nil, err
+	}
+	if b.Metric != metric || b.Policy != policy.String() {
+		return nil, fmt.Errorf("%s: baseline of %s with policy %s cannot be used for %s with policy %s",
+			file, b.Metric, b.Policy, metric, policy)
+	}
+	return b, nil
+}
+
+// writeBaseline writes the baseline b to file.
+func writeBaseline(file string, b *branch.Baseline) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := b.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// analyze analyzes the files, directories and dir/... patterns in args. The
+// analysis of dir/... patterns stops when ctx is done. Syntax errors do not
+// stop the analysis: they are returned in the list, and the packages hold
+// every function that could be parsed. Any other error is returned as err.
+func analyze(ctx context.Context, cfg *branch.Config, args []string) ([]*branch.Package, scanner.ErrorList, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var pkgs []*branch.Package
+	var syntaxErrs scanner.ErrorList
+	add := func(more []*branch.Package, err error) error {
+		pkgs = append(pkgs, more...)
+		if list, ok := err.(scanner.ErrorList); ok {
+			syntaxErrs = append(syntaxErrs, list...)
+			return nil
+		}
+		return err
+	}
+
+	var files []string
+	for _, arg := range args {
+		var err error
+		if root, ok := treeRoot(arg); ok {
+			err = add(cfg.AnalyzeTreeContext(ctx, root))
+		} else if info, statErr := os.Stat(arg); statErr != nil {
+			return nil, nil, statErr
+		} else if info.IsDir() {
+			err = add(cfg.AnalyzeDirContext(ctx, arg))
+		} else {
+			files = append(files, arg)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(files) > 0 {
+		if err := add(cfg.AnalyzeFilesContext(ctx, files...)); err != nil {
+			return nil, nil, err
+		}
+	}
+	syntaxErrs.Sort()
+	return pkgs, syntaxErrs, nil
+}
+
+// treeRoot returns the directory of a "dir/..." pattern.
+func treeRoot(arg string) (string, bool) {
+	if arg == "..." {
+		return ".", true
+	}
+	if strings.HasSuffix(arg, "/...") {
+		return filepath.FromSlash(strings.TrimSuffix(arg, "/...")), true
+	}
+	return "", false
+}
+
+// sortFuncs sorts funcs by decreasing value of the metric, then by identity
+// and position, so the output does not depend on the order of the arguments.
+func sortFuncs(funcs []*branch.Func, metric branch.Metric) {
+	sort.SliceStable(funcs, func(i, j int) bool {
+		a, b := funcs[i], funcs[j]
+		if va, vb := metric.Value(a), metric.Value(b); va != vb {
+			return va > vb
+		}
+		if sa, sb := a.ID.String(), b.ID.String(); sa != sb {
+			return sa < sb
+		}
+		return a.Pos.String() < b.Pos.String()
+	})
+}
+
+// printTable prints one line per function, with its maintainability index.
+func printTable(w io.Writer, funcs []*branch.Func, metric branch.Metric) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tFUNCTION\tPOSITION\tMI\n", strings.ToUpper(metric.String()))
+	for _, fn := range funcs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.1f\n", metric.Value(fn), fn.ID, fn.Pos, fn.Maintainability)
+	}
+	tw.Flush()
+}
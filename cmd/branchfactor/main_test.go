@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package p
+
+func Simple() {
+	if true {
+	}
+}
+
+func Complex(xs []int) {
+	for _, x := range xs {
+		if x > 0 {
+			continue
+		}
+		switch x {
+		case 1:
+		}
+	}
+}
+`
+
+func writeSource(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "p"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := ioutil.WriteFile(filepath.Join(dir, "p", "p.go"), []byte(testSource), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeSource(t)
+
+	tests := []struct {
+		args   []string
+		status int
+		output []string
+	}{
+		{[]string{dir + "/..."}, 0, []string{"BRANCH", "4       example.com/m/p.Complex", "1       example.com/m/p.Simple"}},
+		{[]string{"-max", "4", filepath.Join(dir, "p")}, 0, []string{"p.Complex"}},
+		{[]string{"-max", "3", filepath.Join(dir, "p", "p.go")}, 1,
+			[]string{"p.Complex: branch factor 4 exceeds limit 3"}},
+		{[]string{"-max", "3", "-pkg-max", "example.com/m/...=5", dir + "/..."}, 0, nil},
+		{[]string{"-metric", "cyclomatic", "-max", "3", "-q", dir + "/..."}, 1,
+			[]string{"p.Complex: cyclomatic complexity 4 exceeds limit 3"}},
+		{[]string{"-metric", "lines", dir}, 2, nil},
+		{[]string{"-mi", "sei", dir + "/..."}, 0, []string{"maintainability index (sei): 171 - 5.2*ln(V)"}},
+		{[]string{"-format", "json", dir + "/..."}, 0, []string{`"variant": "microsoft"`, `"files": [`}},
+		{[]string{"-mi", "ibm", dir}, 2, nil},
+		{[]string{"-j", "3", dir + "/..."}, 0, []string{"4       example.com/m/p.Complex"}},
+		{[]string{filepath.Join(dir, "missing.go")}, 2, nil},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(test.args, &stdout, &stderr)
+		if status != test.status {
+			t.Errorf("run(%v) = %d, want %d\nstdout:\n%s\nstderr:\n%s", test.args, status, test.status, &stdout, &stderr)
+		}
+		for _, want := range test.output {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, want)
+			}
+		}
+	}
+}
+
+func TestRunFormats(t *testing.T) {
+	dir := writeSource(t)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `"function": "Complex"`},
+		{"csv", "example.com/m/p,Complex,"},
+		{"sarif", `"ruleId": "max-branch"`},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run([]string{"-format", test.format, "-max", "3", dir + "/..."}, &stdout, &stderr)
+		if status != 1 {
+			t.Errorf("run(-format %s) = %d, want 1\nstderr:\n%s", test.format, status, &stderr)
+		}
+		if !strings.Contains(stdout.String(), test.want) {
+			t.Errorf("run(-format %s) printed\n%s\nwant it to contain %q\n", test.format, &stdout, test.want)
+		}
+	}
+}
+
+func TestRunPolicy(t *testing.T) {
+	dir := writeSource(t)
+	policy := filepath.Join(dir, "policy.json")
+	err := ioutil.WriteFile(policy, []byte(`{"name": "loops", "base": "default", "weights": {"range": 5}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		want   string
+	}{
+		{[]string{"-policy", "structured", dir + "/..."}, 0, "3       example.com/m/p.Complex"},
+		{[]string{"-policy-file", policy, dir + "/..."}, 0, "8       example.com/m/p.Complex"},
+		{[]string{"-policy-file", policy, "-format", "json", dir + "/..."}, 0, `"name": "loops"`},
+		{[]string{"-policy", "lenient", dir}, 2, ""},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if status := run(test.args, &stdout, &stderr); status != test.status {
+			t.Errorf("run(%v) = %d, want %d\nstderr:\n%s", test.args, status, test.status, &stderr)
+		}
+		if !strings.Contains(stdout.String(), test.want) {
+			t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, test.want)
+		}
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	base := writeSource(t)
+	head := writeSource(t)
+	// Simple gets worse, Complex gets better
+	changed := strings.Replace(testSource, "if true {\n\t}", "if true {\n\t}\n\tfor {\n\t}", 1)
+	changed = strings.Replace(changed, "\t\tswitch x {\n\t\tcase 1:\n\t\t}\n", "", 1)
+	if err := ioutil.WriteFile(filepath.Join(head, "p", "p.go"), []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		output []string
+	}{
+		{[]string{"-base", base + "/...", base + "/..."}, 0, nil},
+		{[]string{"-q", "-base", head + "/...", base + "/..."}, 1,
+			[]string{"p.Complex: branch factor increased from 3 to 4 (+1) (worse)"}},
+		{[]string{"-base", base + "/...", head + "/..."}, 1,
+			[]string{"p.Complex: branch factor decreased from 4 to 3 (-1)", "p.Simple: branch factor increased from 1 to 2 (+1) (worse)"}},
+		{[]string{"-base", base + "/...", "-format", "json", head + "/..."}, 1, []string{`"change": "increased"`, `"delta": -1`}},
+		{[]string{"-base", base + "/...", "-format", "csv", head + "/..."}, 2, nil},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(test.args, &stdout, &stderr)
+		if status != test.status {
+			t.Errorf("run(%v) = %d, want %d\nstdout:\n%s\nstderr:\n%s", test.args, status, test.status, &stdout, &stderr)
+		}
+		for _, want := range test.output {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, want)
+			}
+		}
+	}
+}
+
+func TestRunBaseline(t *testing.T) {
+	dir := writeSource(t)
+	file := filepath.Join(dir, "baseline.json")
+	// a custom policy that reuses the name of the default one
+	policy := filepath.Join(dir, "policy.json")
+	err := ioutil.WriteFile(policy, []byte(`{"name": "default", "base": "default", "weights": {"range": 5}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		output []string
+	}{
+		{[]string{"-max", "2", dir + "/..."}, 1, []string{"p.Complex: branch factor 4 exceeds limit 2"}},
+		{[]string{"-write-baseline", file, dir + "/..."}, 0, []string{"wrote baseline of 2 functions"}},
+		{[]string{"-max", "2", "-baseline", file, dir + "/..."}, 0, nil},
+		{[]string{"-max", "2", "-baseline", file, "-metric", "cyclomatic", dir + "/..."}, 2, nil},
+		{[]string{"-max", "2", "-baseline", file, "-policy-file", policy, dir + "/..."}, 2, nil},
+		{[]string{"-max", "2", "-baseline", filepath.Join(dir, "missing.json"), dir + "/..."}, 2, nil},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(test.args, &stdout, &stderr)
+		if status != test.status {
+			t.Errorf("run(%v) = %d, want %d\nstdout:\n%s\nstderr:\n%s", test.args, status, test.status, &stdout, &stderr)
+		}
+		for _, want := range test.output {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, want)
+			}
+		}
+	}
+
+	// Complex grows beyond its baseline
+	grown := strings.Replace(testSource, "continue", "continue\n\t\t\tgoto L", 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, "p", "p.go"), []byte(grown), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-max", "2", "-baseline", file, dir + "/..."}, &stdout, &stderr); status != 1 {
+		t.Errorf("run(grown) = %d, want 1\nstdout:\n%s\nstderr:\n%s", status, &stdout, &stderr)
+	}
+}
+
+func TestRunDirectives(t *testing.T) {
+	dir := writeSource(t)
+	annotated := strings.Replace(testSource, "func Complex", "//branch:ignore\nfunc Complex", 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, "p", "p.go"), []byte(annotated), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		status int
+		output []string
+	}{
+		{[]string{"-max", "3", "-q", dir + "/..."}, 0, []string{"p.Complex: warning: //branch:ignore: directive has no reason"}},
+		{[]string{"-max", "3", "-format", "json", dir + "/..."}, 0, []string{`"warnings": [`, `"directive": "branch:ignore"`}},
+		{[]string{"-max", "3", "-format", "sarif", dir + "/..."}, 0, []string{`"ruleId": "directive"`, `"level": "warning"`}},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(test.args, &stdout, &stderr)
+		if status != test.status {
+			t.Errorf("run(%v) = %d, want %d\nstdout:\n%s\nstderr:\n%s", test.args, status, test.status, &stdout, &stderr)
+		}
+		for _, want := range test.output {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, want)
+			}
+		}
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	dir := writeSource(t)
+	args := []string{"-cache", filepath.Join(dir, "cache"), dir + "/..."}
+
+	var want bytes.Buffer
+	var stderr bytes.Buffer
+	if status := run(args, &want, &stderr); status != 0 {
+		t.Fatalf("run(%v) = %d, want 0\nstderr:\n%s", args, status, &stderr)
+	}
+	if !strings.Contains(stderr.String(), "cache: 0 hits, 1 misses") {
+		t.Errorf("run(%v) printed %q to stderr, want the cache statistics\n", args, &stderr)
+	}
+
+	var got bytes.Buffer
+	stderr.Reset()
+	if status := run(args, &got, &stderr); status != 0 {
+		t.Fatalf("run(%v) = %d, want 0\nstderr:\n%s", args, status, &stderr)
+	}
+	if !strings.Contains(stderr.String(), "cache: 1 hits, 0 misses") {
+		t.Errorf("run(%v) printed %q to stderr, want the cache statistics\n", args, &stderr)
+	}
+	if got.String() != want.String() {
+		t.Errorf("run(%v) printed\n%s\nwith the cache, want\n%s", args, &got, &want)
+	}
+}
+
+func TestRunSyntaxErrors(t *testing.T) {
+	dir := writeSource(t)
+	if err := os.MkdirAll(filepath.Join(dir, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	broken := "package b\n\nfunc Broken() {\n\tif {\n\t}\n}\n\nfunc Worse( {\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "b", "b.go"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		output []string
+	}{
+		{[]string{"-max", "3", dir + "/..."}, []string{"p.Complex: branch factor 4 exceeds limit 3"}},
+		{[]string{"-max", "3", "-format", "sarif", dir + "/..."}, []string{`"ruleId": "max-branch"`}},
+		{[]string{"-max", "3", filepath.Join(dir, "p", "p.go"), filepath.Join(dir, "b", "b.go")},
+			[]string{"p.Complex: branch factor 4 exceeds limit 3"}},
+		{[]string{"-write-baseline", filepath.Join(dir, "baseline.json"), dir + "/..."}, nil},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if status := run(test.args, &stdout, &stderr); status != 2 {
+			t.Errorf("run(%v) = %d, want 2\nstdout:\n%s\nstderr:\n%s", test.args, status, &stdout, &stderr)
+		}
+		for _, want := range test.output {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("run(%v) printed\n%s\nwant it to contain %q\n", test.args, &stdout, want)
+			}
+		}
+		// every syntax error is printed, not just the first one
+		if lines := strings.Count(stderr.String(), "b.go:"); lines < 2 {
+			t.Errorf("run(%v) printed %d syntax errors to stderr, want all of them\n%s", test.args, lines, &stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "baseline.json")); err == nil {
+		t.Errorf("run(-write-baseline) wrote a baseline despite syntax errors\n")
+	}
+}
@@ -0,0 +1,16 @@
+// Command branchvet runs the branchfactor analyzer (see package
+// branchanalysis) on the packages named on the command line, or as a go vet
+// tool:
+//
+//	go vet -vettool=$(which branchvet) -max=15 ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"hw2/branchanalysis"
+)
+
+func main() {
+	singlechecker.Main(branchanalysis.Analyzer)
+}